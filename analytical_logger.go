@@ -0,0 +1,176 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"github.com/cfromknecht/bitcoin_load_spike/queueing"
+	"math"
+)
+
+// Rows per order of magnitude of the analytical CDF
+const ANALYTICAL_POINTS_PER_ORDER = 100
+
+/**
+ * `AnalyticalLogger`
+ *
+ * Writes the analytical confirmation time CDF of a constant load next to the
+ * simulated outputs.  Under a constant load the simulation is a
+ * `queueing.BatchServiceQueue`: `txn`s arrive at `load * BITCOIN_MAX_TPS`,
+ * blocks are mined at `BITCOIN_BLOCK_RATE` and each block confirms up to
+ * `blockSize / BITCOIN_TRANSACTION_SIZE` of the oldest `txn`s.  The logger
+ * ignores the simulated `txn`s.
+ *
+ * Each row of the output file has the format:
+ * `<point-number> | <txn-confirmation-time> | <cumulative-probability>`
+ */
+type AnalyticalLogger struct {
+	queues     []queueing.BatchServiceQueue
+	filePrefix string
+}
+
+/**
+ * Initializes a new `AnalyticalLogger` for each spike of `sp`.
+ *
+ * @param prefix - The file prefix for writing the output file
+ * @param blockSize - The maximum block size in bytes
+ * @param sp - A `SpikeProfile` with a constant load
+ *
+ * @return - The new `AnalyticalLogger`, or an error if the load is not
+ *           constant or exceeds the capacity of the blocks
+ */
+func newAnalyticalLogger(prefix string, blockSize float64, sp *SpikeProfile) (*AnalyticalLogger, error) {
+	load, ok := sp.constantLoad()
+	if !ok {
+		return nil, fmt.Errorf("analytical baseline requires a constant load")
+	}
+
+	q := AnalyticalQueue(blockSize, load)
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	al := &AnalyticalLogger{filePrefix: prefix}
+	for range sp.Spikes {
+		al.queues = append(al.queues, q)
+	}
+	return al, nil
+}
+
+/**
+ * Returns the `SpikeProfile` of a simulation the analytical baseline can
+ * model: a single class of `BITCOIN_TRANSACTION_SIZE` `txn`s paying a
+ * constant fee rate, so that blocks confirm the oldest `txn`s first.
+ *
+ * @param classes - The `TxnClass`es of the simulation
+ *
+ * @return - The `SpikeProfile` of the only class, or an error if the
+ *           classes cannot be modeled
+ */
+func analyticalProfile(classes []TxnClass) (*SpikeProfile, error) {
+	if len(classes) != 1 {
+		return nil, fmt.Errorf("analytical baseline requires a single transaction class")
+	}
+	class := &classes[0]
+	if class.Size != ConstantDistribution(BITCOIN_TRANSACTION_SIZE) {
+		return nil, fmt.Errorf("analytical baseline requires txns of %.0f bytes", BITCOIN_TRANSACTION_SIZE)
+	}
+	if class.FeeRate.Kind != DISTRIBUTION_CONSTANT {
+		return nil, fmt.Errorf("analytical baseline requires a constant fee rate")
+	}
+	if class.Batching != nil || class.FeeTarget > 0 || class.Bidding != nil {
+		return nil, fmt.Errorf("analytical baseline does not model batching, fee estimates or bidding")
+	}
+	return &class.SpikeProfile, nil
+}
+
+/**
+ * Verifies that every `Miner` fills its blocks up to the maximum block size,
+ * as the analytical baseline assumes, and that no blocks are mined empty by
+ * `SPVMining` or orphaned by `Propagation`.  With a single constant fee rate,
+ * the `fee` and `fifo` policies both include the oldest `txn`s first.
+ *
+ * @param miners - The `Miner`s of the simulation
+ * @param spv - The `SPVMining` of the simulation, or nil
+ * @param p - The `Propagation` of the simulation, or nil
+ *
+ * @return - nil if the baseline can model the mining of blocks
+ */
+func analyticalMining(miners []Miner, spv *SPVMining, p *Propagation) error {
+	if spv != nil && spv.Probability > 0.0 {
+		return fmt.Errorf("analytical baseline does not model SPV mining")
+	}
+	if p != nil && (p.Latency > 0.0 || p.SecondsPerMB > 0.0) {
+		return fmt.Errorf("analytical baseline does not model propagation delays")
+	}
+	for _, m := range miners {
+		if m.Policy == TEMPLATE_EMPTY || m.SoftLimit > 0.0 {
+			return fmt.Errorf("analytical baseline requires miners that fill their blocks, %q does not", m.Name)
+		}
+	}
+	return nil
+}
+
+/**
+ * Builds the queue modeled by a simulation with a constant `load`.
+ *
+ * @param blockSize - The maximum block size in bytes
+ * @param load - The percentage of `BITCOIN_MAX_TPS`
+ *
+ * @return - The equivalent `queueing.BatchServiceQueue`
+ */
+func AnalyticalQueue(blockSize, load float64) queueing.BatchServiceQueue {
+	return queueing.BatchServiceQueue{
+		ArrivalRate: load * BITCOIN_MAX_TPS,
+		ServiceRate: BITCOIN_BLOCK_RATE,
+		BatchSize:   int64(blockSize / BITCOIN_TRANSACTION_SIZE),
+	}
+}
+
+/**
+ * @return - The specified prefix for the output file.
+ */
+func (al AnalyticalLogger) FilePrefix() string {
+	return al.filePrefix
+}
+
+/**
+ * @return - The file extension for an `AnalyticalLogger`
+ */
+func (al AnalyticalLogger) FileExtension() string {
+	return "an-dat"
+}
+
+/**
+ * The analytical CDF does not depend on the simulated `txn`s.
+ */
+func (al *AnalyticalLogger) Log(blockTimestamp float64, t txn) {}
+
+/**
+ * Tabulates the analytical CDF on a log scale until it reaches 1 - 1e-6.
+ *
+ * @return - The file contents for each `Spike` in the `SpikeProfile`
+ */
+func (al *AnalyticalLogger) Outputs() (outputs []string) {
+	for i, q := range al.queues {
+		fmt.Println("[AnalyticalLogger]: generating analytical plot data for spike", i)
+		fmt.Println(fmt.Sprintf("     utilization %.4f, mean %f, median %f, p95 %f, p99 %f",
+			q.Utilization(), q.MeanWaitingTime(),
+			q.WaitingQuantile(0.5), q.WaitingQuantile(0.95), q.WaitingQuantile(0.99)))
+
+		fileContents := ""
+		for j := 0; j <= ANALYTICAL_POINTS_PER_ORDER*(POSITIVE_ORDERS+NEGATIVE_ORDERS); j++ {
+			t := math.Pow(10.0, float64(j)/ANALYTICAL_POINTS_PER_ORDER-NEGATIVE_ORDERS)
+			probability := q.WaitingCDF(t)
+			fileContents += fmt.Sprintf("%d | %f | %f\n", j, t, probability)
+			if probability >= 1-1e-6 {
+				break
+			}
+		}
+		outputs = append(outputs, fileContents)
+	}
+	return
+}
+
+/**
+ * Nothing to clear, the analytical CDF is fixed.
+ */
+func (al *AnalyticalLogger) Reset() {}
@@ -0,0 +1,58 @@
+package bitcoin_load_spike
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAnalyticalLoggerRequiresConstantLoad(t *testing.T) {
+	spike := &SpikeProfile{[]Spike{Spike{0.0, 0.1}, Spike{0.5, 0.2}}}
+	if _, err := newAnalyticalLogger("", DEFAULT_BLOCK_SIZE, spike); err == nil {
+		t.Error("Expected an error for a spike profile")
+	}
+
+	overloaded := &SpikeProfile{[]Spike{Spike{0.0, 1.0}}}
+	if _, err := newAnalyticalLogger("", DEFAULT_BLOCK_SIZE, overloaded); err == nil {
+		t.Error("Expected an error for a load beyond the block capacity")
+	}
+}
+
+func TestAnalyticalLoggerOutput(t *testing.T) {
+	al, err := newAnalyticalLogger("", DEFAULT_BLOCK_SIZE, &SpikeProfile{[]Spike{Spike{0.0, 0.1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := strings.Split(strings.TrimSpace(al.Outputs()[0]), "\n")
+	if rows[0] != "0 | 0.100000 | 0.000166" {
+		t.Error("Unexpected first row", rows[0])
+	}
+	if !strings.HasSuffix(rows[len(rows)-1], "| 0.999999") && !strings.HasSuffix(rows[len(rows)-1], "| 1.000000") {
+		t.Error("Expected the CDF to reach 1, got", rows[len(rows)-1])
+	}
+}
+
+func TestSimulationMatchesAnalyticalQueue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping long simulation in short mode")
+	}
+
+	// 70% of the block capacity, past the warm-up the backlog is stationary
+	load := 0.4
+	q := AnalyticalQueue(DEFAULT_BLOCK_SIZE, load)
+	results := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(2000), int64(2)).
+		UseSpikeProfile(&SpikeProfile{[]Spike{Spike{0.0, load}}}).
+		UseWarmUp(100, 0).
+		UseSeed(11).
+		Simulate()
+
+	distance := 0.0
+	for _, p := range []float64{0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99} {
+		tq := q.WaitingQuantile(p)
+		distance = math.Max(distance, math.Abs(results.Probability(0, tq)-p))
+	}
+	if distance > 0.03 {
+		t.Error("Expected the simulated CDF within 0.03 of the analytical CDF, got", distance)
+	}
+}
@@ -0,0 +1,224 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+ * `BacklogLogger`
+ *
+ * Records the number and total size of unconfirmed `txn`s of each `TxnClass`
+ * in the `mempool` over time.  The backlog is integrated over time, so each
+ * bucket reports the time weighted average backlog across all iterations that
+ * reached it.  There is one output per class.
+ *
+ * Each row of the output file has the format:
+ * `<bucket-number> | <bucket-start-time> | <mean-txn-count> | <mean-bytes>`
+ */
+type BacklogLogger struct {
+	secsPerBucket float64
+	filePrefix    string
+
+	// Integrals of the backlog of each class over time and the time observed
+	// per bucket
+	counts   [][]float64
+	sizes    [][]float64
+	coverage []float64
+
+	// Backlog of each class since the most recent event in the current
+	// iteration
+	lastTimestamp float64
+	lastCounts    []int
+	lastSizes     []float64
+}
+
+/**
+ * Initializes a new `BacklogLogger`
+ *
+ * @param prefix - The file prefix for writing the output files
+ * @param secsPerBucket - The width of each time bucket in seconds
+ * @param numClasses - The number of `TxnClass`es to record separately
+ *
+ * @return - An empty `BacklogLogger`
+ */
+func newBacklogLogger(prefix string, secsPerBucket float64, numClasses int) *BacklogLogger {
+	bl := &BacklogLogger{
+		secsPerBucket: secsPerBucket,
+		filePrefix:    prefix,
+		counts:        make([][]float64, numClasses),
+		sizes:         make([][]float64, numClasses),
+		lastCounts:    make([]int, numClasses),
+		lastSizes:     make([]float64, numClasses),
+	}
+	bl.Reset()
+
+	return bl
+}
+
+/**
+ * @return - The specified prefix for the output file.
+ */
+func (bl BacklogLogger) FilePrefix() string {
+	return bl.filePrefix
+}
+
+/**
+ * @return - The file extension for `BacklogLogger` output
+ */
+func (bl BacklogLogger) FileExtension() string {
+	return "bl-dat"
+}
+
+/**
+ * Confirmed `txn`s are accounted for by `LogBlock`.
+ */
+func (bl *BacklogLogger) Log(blockTimestamp float64, t txn) {}
+
+/**
+ * Records the `mempool` after a `txn` arrives.
+ *
+ * @param t - The arriving `txn`
+ * @param pool - The `mempool` after `t` was added
+ */
+func (bl *BacklogLogger) LogArrival(t txn, pool *mempool) {
+	bl.observe(t.time, pool)
+}
+
+/**
+ * Records the `mempool` after a block is mined.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (bl *BacklogLogger) LogBlock(b block, pool *mempool) {
+	bl.observe(b.timestamp, pool)
+}
+
+/**
+ * Integrates the backlog up to the end of the iteration and prepares for the
+ * next iteration, which starts with an empty `mempool`.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (bl *BacklogLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	bl.integrate(endTimestamp)
+	bl.clearLast()
+}
+
+/**
+ * Integrates the previous backlog up to `timestamp` and stores the new one.
+ */
+func (bl *BacklogLogger) observe(timestamp float64, pool *mempool) {
+	bl.integrate(timestamp)
+
+	for k := range bl.lastCounts {
+		bl.lastCounts[k] = pool.ClassLen(k)
+		bl.lastSizes[k] = pool.ClassSize(k)
+	}
+}
+
+/**
+ * Adds the most recent backlog, weighted by the time it persisted, to every
+ * bucket between the previous event and `until`.
+ */
+func (bl *BacklogLogger) integrate(until float64) {
+	start := bl.lastTimestamp
+	for start < until {
+		b := int64(start / bl.secsPerBucket)
+		// Guard against rounding placing `start` on the previous bucket
+		if float64(b+1)*bl.secsPerBucket <= start {
+			b++
+		}
+		end := math.Min(until, float64(b+1)*bl.secsPerBucket)
+
+		bl.extend(b)
+		dt := end - start
+		for k := range bl.counts {
+			bl.counts[k][b] += float64(bl.lastCounts[k]) * dt
+			bl.sizes[k][b] += bl.lastSizes[k] * dt
+		}
+		bl.coverage[b] += dt
+
+		start = end
+	}
+
+	if until > bl.lastTimestamp {
+		bl.lastTimestamp = until
+	}
+}
+
+/**
+ * Extends the buckets so that bucket `b` exists.
+ */
+func (bl *BacklogLogger) extend(b int64) {
+	if b < int64(len(bl.coverage)) {
+		return
+	}
+
+	diff := b - int64(len(bl.coverage)) + 1
+	for k := range bl.counts {
+		bl.counts[k] = append(bl.counts[k], make([]float64, diff)...)
+		bl.sizes[k] = append(bl.sizes[k], make([]float64, diff)...)
+	}
+	bl.coverage = append(bl.coverage, make([]float64, diff)...)
+}
+
+/**
+ * Generates the backlog time series file contents.
+ *
+ * @return - An output for each `TxnClass` containing every time bucket
+ */
+func (bl *BacklogLogger) Outputs() (outputs []string) {
+	fmt.Println("[BacklogLogger]: generating backlog plot")
+
+	for k := range bl.counts {
+		fileContents := ""
+		for i := range bl.coverage {
+			if bl.coverage[i] == 0.0 {
+				continue
+			}
+
+			fileContents += fmt.Sprintf("%d | %f | %f | %f\n",
+				i,
+				float64(i)*bl.secsPerBucket,
+				bl.counts[k][i]/bl.coverage[i],
+				bl.sizes[k][i]/bl.coverage[i])
+		}
+		outputs = append(outputs, fileContents)
+	}
+
+	return
+}
+
+/**
+ * @return - Whether the outputs are per `TxnClass`, always true
+ */
+func (bl *BacklogLogger) ByClass() bool {
+	return true
+}
+
+/**
+ * Clears the logging state.
+ */
+func (bl *BacklogLogger) Reset() {
+	for k := range bl.counts {
+		bl.counts[k] = []float64{}
+		bl.sizes[k] = []float64{}
+	}
+	bl.coverage = []float64{}
+
+	bl.clearLast()
+}
+
+/**
+ * Starts an iteration with an empty `mempool`.
+ */
+func (bl *BacklogLogger) clearLast() {
+	bl.lastTimestamp = 0.0
+	for k := range bl.lastCounts {
+		bl.lastCounts[k] = 0
+		bl.lastSizes[k] = 0.0
+	}
+}
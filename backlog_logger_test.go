@@ -0,0 +1,50 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestBacklogFileExtension(t *testing.T) {
+	expectedExtension := "bl-dat"
+
+	bl := newBacklogLogger("", DEFAULT_SECS_PER_BUCKET, 1)
+
+	if bl.FileExtension() != expectedExtension {
+		t.Error("Expected file extension", expectedExtension, ", got", bl.FileExtension())
+	}
+}
+
+func TestBacklogIntegration(t *testing.T) {
+	bl := newBacklogLogger("", 100.0, 1)
+	pool := newMempool()
+
+	// One txn waits from 50 to 150, a second from 120 to 150
+	pool.add(newTestTxn(50.0, 0))
+	bl.LogArrival(newTestTxn(50.0, 0), pool)
+	pool.add(newTestTxn(120.0, 0))
+	bl.LogArrival(newTestTxn(120.0, 0), pool)
+	pool.fillBlock(DEFAULT_BLOCK_SIZE)
+	bl.LogBlock(block{timestamp: 150.0}, pool)
+	bl.EndIteration(200.0, pool)
+
+	expectedOutput := "0 | 0.000000 | 0.500000 | 436.500000\n" +
+		"1 | 100.000000 | 0.800000 | 698.400000\n"
+
+	output := bl.Outputs()[0]
+	if output != expectedOutput {
+		t.Error("Expected output '", expectedOutput, "', got '", output, "'")
+	}
+}
+
+func TestBacklogAveragesIterations(t *testing.T) {
+	bl := newBacklogLogger("", 100.0, 1)
+	pool := newMempool()
+
+	// First iteration has a backlog of 1 txn, the second is empty
+	pool.add(newTestTxn(0.0, 0))
+	bl.LogArrival(newTestTxn(0.0, 0), pool)
+	bl.EndIteration(100.0, pool)
+	bl.EndIteration(100.0, newMempool())
+
+	if bl.counts[0][0]/bl.coverage[0] != 0.5 {
+		t.Error("Expected average backlog of 0.5, got", bl.counts[0][0]/bl.coverage[0])
+	}
+}
@@ -0,0 +1,327 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Kinds of `BlockSizePolicyConfig`
+const (
+	BLOCK_SIZE_FIXED     = "fixed"
+	BLOCK_SIZE_SCHEDULED = "scheduled"
+	BLOCK_SIZE_VOTING    = "voting"
+	BLOCK_SIZE_MEDIAN    = "median"
+)
+
+/**
+ * `BlockSizePolicy`
+ *
+ * Decides the maximum size of each block of the running simulation, so
+ * scaling proposals can be compared under the same spikes.  Each iteration
+ * starts from the simulation's block size.
+ */
+type BlockSizePolicy interface {
+	// Returns the maximum size in bytes of the block at `height`
+	MaxBlockSize(height int64) float64
+	// Observes a block of the winning chain
+	ObserveBlock(b block)
+	// Forgets the observed blocks before a new iteration
+	Reset()
+}
+
+/**
+ * `FixedBlockSize`
+ *
+ * A `BlockSizePolicy` that keeps the maximum block size at `Size` bytes.
+ */
+type FixedBlockSize struct {
+	Size float64
+}
+
+func (fbs *FixedBlockSize) MaxBlockSize(height int64) float64 {
+	return fbs.Size
+}
+
+func (fbs *FixedBlockSize) ObserveBlock(b block) {}
+
+func (fbs *FixedBlockSize) Reset() {}
+
+/**
+ * `ScheduledBlockSize`
+ *
+ * A `BlockSizePolicy` modeled on BIP101, which doubles the maximum block size
+ * every `Interval` blocks, interpolating linearly between doublings, until it
+ * reaches `MaxSize` bytes.
+ */
+type ScheduledBlockSize struct {
+	Initial  float64
+	Interval int64
+	MaxSize  float64
+}
+
+/**
+ * Initializes a new `ScheduledBlockSize`
+ *
+ * @param initial - The maximum block size in bytes at height 0
+ * @param interval - The number of blocks between doublings
+ * @param maxSize - The largest maximum block size in bytes, 0 for no limit
+ *
+ * @return - The new `ScheduledBlockSize`
+ */
+func NewScheduledBlockSize(initial float64, interval int64, maxSize float64) *ScheduledBlockSize {
+	return &ScheduledBlockSize{Initial: initial, Interval: interval, MaxSize: maxSize}
+}
+
+/**
+ * @param height - The height of the block
+ *
+ * @return - The scheduled maximum block size in bytes
+ */
+func (sbs *ScheduledBlockSize) MaxBlockSize(height int64) float64 {
+	doublings := height / sbs.Interval
+	progress := float64(height%sbs.Interval) / float64(sbs.Interval)
+	size := sbs.Initial * math.Pow(2.0, float64(doublings)) * (1.0 + progress)
+	if sbs.MaxSize > 0.0 {
+		size = math.Min(size, sbs.MaxSize)
+	}
+	return size
+}
+
+func (sbs *ScheduledBlockSize) ObserveBlock(b block) {}
+
+func (sbs *ScheduledBlockSize) Reset() {}
+
+/**
+ * `VotingBlockSize`
+ *
+ * A `BlockSizePolicy` modeled on BIP100, in which every block votes for the
+ * maximum block size of its `Miner`.  At the end of every `Period` blocks the
+ * maximum block size becomes the `Percentile` of the period's votes, e.g. 0.2
+ * for the size that 80% of the blocks voted for or above.  Each change is
+ * limited to a factor of `MaxChange`, 0 for no limit.  `Votes` holds the
+ * vote of each miner, 0 votes for the current size.
+ */
+type VotingBlockSize struct {
+	Initial    float64
+	Period     int64
+	Percentile float64
+	MaxChange  float64
+	Votes      []float64
+
+	current float64
+	period  []float64
+}
+
+/**
+ * Initializes a new `VotingBlockSize`
+ *
+ * @param initial - The maximum block size in bytes of the first period
+ * @param period - The number of blocks between changes
+ * @param percentile - The percentile of the votes that is adopted
+ * @param maxChange - The largest factor of a single change, 0 for no limit
+ * @param votes - The vote in bytes of each `Miner`, 0 to keep the size
+ *
+ * @return - The new `VotingBlockSize`
+ */
+func NewVotingBlockSize(initial float64, period int64, percentile, maxChange float64, votes []float64) *VotingBlockSize {
+	vbs := &VotingBlockSize{
+		Initial:    initial,
+		Period:     period,
+		Percentile: percentile,
+		MaxChange:  maxChange,
+		Votes:      votes,
+	}
+	vbs.Reset()
+	return vbs
+}
+
+func (vbs *VotingBlockSize) MaxBlockSize(height int64) float64 {
+	return vbs.current
+}
+
+/**
+ * Records the vote of the block's miner, and adopts the `Percentile` vote at
+ * the end of a period.
+ *
+ * @param b - The mined block
+ */
+func (vbs *VotingBlockSize) ObserveBlock(b block) {
+	vote := vbs.current
+	if b.miner < len(vbs.Votes) && vbs.Votes[b.miner] > 0.0 {
+		vote = vbs.Votes[b.miner]
+	}
+	vbs.period = append(vbs.period, vote)
+	if int64(len(vbs.period)) < vbs.Period {
+		return
+	}
+
+	sort.Float64s(vbs.period)
+	i := int(math.Ceil(vbs.Percentile*float64(len(vbs.period)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	size := vbs.period[i]
+	if vbs.MaxChange > 0.0 {
+		size = math.Max(vbs.current/vbs.MaxChange, math.Min(vbs.current*vbs.MaxChange, size))
+	}
+	vbs.current = size
+	vbs.period = vbs.period[:0]
+}
+
+func (vbs *VotingBlockSize) Reset() {
+	vbs.current = vbs.Initial
+	vbs.period = []float64{}
+}
+
+/**
+ * `MedianBlockSize`
+ *
+ * A `BlockSizePolicy` modeled on BitPay's adaptive block size proposal, in
+ * which the maximum block size is `Multiplier` times the median size of the
+ * last `Window` blocks, and never less than `Initial` bytes.
+ */
+type MedianBlockSize struct {
+	Initial    float64
+	Window     int64
+	Multiplier float64
+
+	sizes  []float64
+	sorted []float64
+}
+
+/**
+ * Initializes a new `MedianBlockSize`
+ *
+ * @param initial - The smallest maximum block size in bytes
+ * @param window - The number of recent blocks whose median size is used
+ * @param multiplier - The factor applied to the median size
+ *
+ * @return - The new `MedianBlockSize`
+ */
+func NewMedianBlockSize(initial float64, window int64, multiplier float64) *MedianBlockSize {
+	mbs := &MedianBlockSize{Initial: initial, Window: window, Multiplier: multiplier}
+	mbs.Reset()
+	return mbs
+}
+
+/**
+ * @param height - The height of the block
+ *
+ * @return - The maximum block size in bytes given the recent blocks
+ */
+func (mbs *MedianBlockSize) MaxBlockSize(height int64) float64 {
+	n := len(mbs.sorted)
+	if n == 0 {
+		return mbs.Initial
+	}
+
+	median := mbs.sorted[n/2]
+	if n%2 == 0 {
+		median = (mbs.sorted[n/2-1] + mbs.sorted[n/2]) / 2.0
+	}
+	return math.Max(mbs.Initial, mbs.Multiplier*median)
+}
+
+/**
+ * Adds the size of the block to the window, dropping the oldest size once
+ * the window is full.  The sizes are also kept in order, so the median is
+ * found without sorting the window.
+ *
+ * @param b - The mined block
+ */
+func (mbs *MedianBlockSize) ObserveBlock(b block) {
+	mbs.sizes = append(mbs.sizes, b.size)
+	i := sort.SearchFloat64s(mbs.sorted, b.size)
+	mbs.sorted = append(mbs.sorted, 0.0)
+	copy(mbs.sorted[i+1:], mbs.sorted[i:])
+	mbs.sorted[i] = b.size
+
+	if int64(len(mbs.sizes)) > mbs.Window {
+		oldest := mbs.sizes[0]
+		mbs.sizes = mbs.sizes[1:]
+		j := sort.SearchFloat64s(mbs.sorted, oldest)
+		mbs.sorted = append(mbs.sorted[:j], mbs.sorted[j+1:]...)
+	}
+}
+
+func (mbs *MedianBlockSize) Reset() {
+	mbs.sizes = []float64{}
+	mbs.sorted = []float64{}
+}
+
+/**
+ * `BlockSizePolicyConfig`
+ *
+ * Describes a `BlockSizePolicy` starting from the simulation's block size.
+ * The parameters used depend on the `Kind`:
+ *   fixed:     none
+ *   scheduled: `Interval`, `MaxSize`
+ *   voting:    `Period`, `Percentile`, `MaxChange` and the `Vote` of each
+ *              `Miner`
+ *   median:    `Window`, `Multiplier`
+ */
+type BlockSizePolicyConfig struct {
+	Kind       string  `json:"kind"`
+	Interval   int64   `json:"interval,omitempty"`
+	MaxSize    float64 `json:"max_size,omitempty"`
+	Period     int64   `json:"period,omitempty"`
+	Percentile float64 `json:"percentile,omitempty"`
+	MaxChange  float64 `json:"max_change,omitempty"`
+	Window     int64   `json:"window,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+/**
+ * Verifies that the parameters of the `Kind` are valid.
+ *
+ * @return - nil if the `BlockSizePolicyConfig` is valid, otherwise the first
+ *           problem
+ */
+func (c BlockSizePolicyConfig) Validate() error {
+	switch c.Kind {
+	case BLOCK_SIZE_FIXED:
+	case BLOCK_SIZE_SCHEDULED:
+		if c.Interval < 1 || c.MaxSize < 0.0 {
+			return fmt.Errorf("scheduled block size requires a positive interval")
+		}
+	case BLOCK_SIZE_VOTING:
+		if c.Period < 1 || c.Percentile <= 0.0 || c.Percentile > 1.0 {
+			return fmt.Errorf("voting block size requires a positive period and a percentile in (0, 1]")
+		}
+		if c.MaxChange != 0.0 && c.MaxChange < 1.0 {
+			return fmt.Errorf("voting block size maximum change %f must be at least 1", c.MaxChange)
+		}
+	case BLOCK_SIZE_MEDIAN:
+		if c.Window < 1 || c.Multiplier <= 0.0 {
+			return fmt.Errorf("median block size requires a positive window and multiplier")
+		}
+	default:
+		return fmt.Errorf("unknown block size policy %q", c.Kind)
+	}
+	return nil
+}
+
+/**
+ * Builds the `BlockSizePolicy` described by the config.
+ *
+ * @param blockSize - The initial maximum block size in bytes
+ * @param miners - The `Miner`s of the simulation, whose votes are used
+ *
+ * @return - The new `BlockSizePolicy`
+ */
+func (c BlockSizePolicyConfig) Policy(blockSize float64, miners []Miner) BlockSizePolicy {
+	switch c.Kind {
+	case BLOCK_SIZE_SCHEDULED:
+		return NewScheduledBlockSize(blockSize, c.Interval, c.MaxSize)
+	case BLOCK_SIZE_VOTING:
+		votes := []float64{}
+		for _, m := range miners {
+			votes = append(votes, m.Vote)
+		}
+		return NewVotingBlockSize(blockSize, c.Period, c.Percentile, c.MaxChange, votes)
+	case BLOCK_SIZE_MEDIAN:
+		return NewMedianBlockSize(blockSize, c.Window, c.Multiplier)
+	}
+	return &FixedBlockSize{Size: blockSize}
+}
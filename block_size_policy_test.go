@@ -0,0 +1,82 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestScheduledBlockSize(t *testing.T) {
+	sbs := NewScheduledBlockSize(1000.0, 10, 3000.0)
+	expected := map[int64]float64{0: 1000.0, 5: 1500.0, 10: 2000.0, 15: 3000.0, 40: 3000.0}
+	for height, size := range expected {
+		if max := sbs.MaxBlockSize(height); max != size {
+			t.Error("Expected maximum size", size, "at height", height, ", got", max)
+		}
+	}
+}
+
+func TestVotingBlockSize(t *testing.T) {
+	// Miner 1 keeps the current size
+	vbs := NewVotingBlockSize(1000.0, 5, 0.2, 1.5, []float64{4000.0, 0.0})
+
+	for _, miner := range []int{0, 0, 0, 0, 0} {
+		vbs.ObserveBlock(block{miner: miner})
+	}
+	if size := vbs.MaxBlockSize(5); size != 1500.0 {
+		t.Error("Expected the change to be limited to 1500, got", size)
+	}
+
+	for _, miner := range []int{0, 0, 0, 0, 1} {
+		vbs.ObserveBlock(block{miner: miner})
+	}
+	if size := vbs.MaxBlockSize(10); size != 1500.0 {
+		t.Error("Expected the 20th percentile vote of 1500, got", size)
+	}
+
+	vbs.Reset()
+	if size := vbs.MaxBlockSize(0); size != 1000.0 {
+		t.Error("Expected reset to restore the initial size, got", size)
+	}
+}
+
+func TestMedianBlockSize(t *testing.T) {
+	mbs := NewMedianBlockSize(1000.0, 3, 2.0)
+	if size := mbs.MaxBlockSize(0); size != 1000.0 {
+		t.Error("Expected the initial size without blocks, got", size)
+	}
+
+	for _, size := range []float64{200.0, 900.0, 800.0, 1000.0} {
+		mbs.ObserveBlock(block{size: size})
+	}
+	// The median of the last 3 blocks is 900
+	if size := mbs.MaxBlockSize(4); size != 1800.0 {
+		t.Error("Expected twice the median size, got", size)
+	}
+
+	mbs.ObserveBlock(block{size: 100.0})
+	mbs.ObserveBlock(block{size: 100.0})
+	if size := mbs.MaxBlockSize(6); size != 1000.0 {
+		t.Error("Expected the initial size as a minimum, got", size)
+	}
+}
+
+func TestBlockSizePolicyConfig(t *testing.T) {
+	miners := []Miner{Miner{Name: "big", Hashrate: 1.0, Policy: TEMPLATE_FEE, Vote: 8e6}}
+	voting := BlockSizePolicyConfig{Kind: BLOCK_SIZE_VOTING, Period: 2016, Percentile: 0.2}
+	if err := voting.Validate(); err != nil {
+		t.Fatal("Expected voting policy to be valid, got", err)
+	}
+	policy, ok := voting.Policy(1e6, miners).(*VotingBlockSize)
+	if !ok || len(policy.Votes) != 1 || policy.Votes[0] != 8e6 || policy.MaxBlockSize(0) != 1e6 {
+		t.Error("Expected a voting policy with the votes of the miners, got", policy)
+	}
+
+	invalid := []BlockSizePolicyConfig{
+		BlockSizePolicyConfig{Kind: "bip999"},
+		BlockSizePolicyConfig{Kind: BLOCK_SIZE_SCHEDULED},
+		BlockSizePolicyConfig{Kind: BLOCK_SIZE_VOTING, Period: 2016, Percentile: 1.5},
+		BlockSizePolicyConfig{Kind: BLOCK_SIZE_MEDIAN, Window: 100},
+	}
+	for _, c := range invalid {
+		if c.Validate() == nil {
+			t.Error("Expected", c, "to be invalid")
+		}
+	}
+}
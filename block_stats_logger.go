@@ -0,0 +1,207 @@
+package bitcoin_load_spike
+
+import "fmt"
+
+/**
+ * `BlockStatsLogger`
+ *
+ * Records statistics of every mined block, aggregated by block height across
+ * iterations.  Since `SpikeProfile`s are defined in terms of block heights,
+ * each row describes the blocks mined at the same point of every spike.
+ * There is one output per `TxnClass`, in which the number of `txn`s and the
+ * fees only count the `txn`s of the class.
+ *
+ * Each row of the output file has the format:
+ * `<height> | <mean-interval> | <mean-fill-ratio> | <fraction-full> | <mean-txns> | <mean-fees> | <mean-min-fee-rate> | <fraction-spv> | <mean-max-size> | <mean-size> | <mean-penalty>`
+ * where a block is full if it has no room for another `BITCOIN_TRANSACTION_SIZE`
+ * `txn`, the fill ratio is the size of a block over its maximum size, fees
+ * are in satoshis, the minimum fee rate in satoshis per byte is
+ * the lowest of any class included in the block, averaged over the blocks
+ * that included `txn`s, SPV blocks were mined empty by `SPVMining`,
+ * the maximum size in bytes is set by the `BlockSizePolicy` and the penalty
+ * in satoshis was paid to exceed it, see `Penalty`.  The fill ratio of a
+ * penalized block is above 1.
+ */
+type BlockStatsLogger struct {
+	heights    []*blockStats
+	numClasses int
+	filePrefix string
+}
+
+/**
+ * Initializes a new `BlockStatsLogger`
+ *
+ * @param prefix - The file prefix for writing the output files
+ * @param numClasses - The number of `TxnClass`es to record separately
+ *
+ * @return - An empty `BlockStatsLogger`
+ */
+func newBlockStatsLogger(prefix string, numClasses int) *BlockStatsLogger {
+	return &BlockStatsLogger{
+		heights:    []*blockStats{},
+		numClasses: numClasses,
+		filePrefix: prefix,
+	}
+}
+
+/**
+ * @return - The specified prefix for the output file.
+ */
+func (bsl BlockStatsLogger) FilePrefix() string {
+	return bsl.filePrefix
+}
+
+/**
+ * @return - The file extension for `BlockStatsLogger` output
+ */
+func (bsl BlockStatsLogger) FileExtension() string {
+	return "bsl-dat"
+}
+
+/**
+ * Confirmed `txn`s are accounted for by `LogBlock`.
+ */
+func (bsl *BlockStatsLogger) Log(blockTimestamp float64, t txn) {}
+
+/**
+ * Records the statistics of a mined block under its height.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (bsl *BlockStatsLogger) LogBlock(b block, pool *mempool) {
+	if b.height >= int64(len(bsl.heights)) {
+		extension := make([]*blockStats, b.height-int64(len(bsl.heights))+1)
+		bsl.heights = append(bsl.heights, extension...)
+	}
+	if bsl.heights[b.height] == nil {
+		bsl.heights[b.height] = newBlockStats(bsl.numClasses)
+	}
+
+	bsl.heights[b.height].add(b)
+}
+
+/**
+ * Generates the block statistics file contents.
+ *
+ * @return - An output for each `TxnClass` containing every block height
+ */
+func (bsl *BlockStatsLogger) Outputs() (outputs []string) {
+	fmt.Println("[BlockStatsLogger]: generating block statistics")
+
+	for k := 0; k < bsl.numClasses; k++ {
+		fileContents := ""
+		for i, stats := range bsl.heights {
+			if stats == nil {
+				continue
+			}
+			fileContents += stats.output(i, k)
+		}
+		outputs = append(outputs, fileContents)
+	}
+
+	return
+}
+
+/**
+ * @return - Whether the outputs are per `TxnClass`, always true
+ */
+func (bsl *BlockStatsLogger) ByClass() bool {
+	return true
+}
+
+/**
+ * Clears the logging state.
+ */
+func (bsl *BlockStatsLogger) Reset() {
+	bsl.heights = []*blockStats{}
+}
+
+/**
+ * Accumulates the statistics of all blocks mined at a single height.
+ */
+type blockStats struct {
+	numBlocks     int64
+	numFull       int64
+	numSPV        int64
+	numNonEmpty   int64
+	totalInterval float64
+	totalFill     float64
+	totalMaxSize  float64
+	totalSize     float64
+	totalPenalty  float64
+	totalMinRate  float64
+	totalTxns     []int64
+	totalFees     []float64
+}
+
+/**
+ * Initializes empty `blockStats`
+ *
+ * @param numClasses - The number of `TxnClass`es to record separately
+ *
+ * @return - The new `blockStats`
+ */
+func newBlockStats(numClasses int) *blockStats {
+	return &blockStats{
+		totalTxns: make([]int64, numClasses),
+		totalFees: make([]float64, numClasses),
+	}
+}
+
+/**
+ * Adds a block to the accumulated statistics.
+ *
+ * @param b - The mined block
+ */
+func (bs *blockStats) add(b block) {
+	bs.numBlocks++
+	bs.totalInterval += b.interval
+	bs.totalFill += b.size / b.maxSize
+	bs.totalMaxSize += b.maxSize
+	bs.totalSize += b.size
+	bs.totalPenalty += b.penalty
+	if rate, ok := b.minFeeRate(); ok {
+		bs.numNonEmpty++
+		bs.totalMinRate += rate
+	}
+	for _, t := range b.txns {
+		bs.totalTxns[t.class]++
+		bs.totalFees[t.class] += t.feeRate * t.size
+	}
+
+	if b.full() {
+		bs.numFull++
+	}
+	if b.spv {
+		bs.numSPV++
+	}
+}
+
+/**
+ * Returns a string representation of the statistics for height `i`.
+ *
+ * @param i - The block height
+ * @param class - The `TxnClass` whose `txn`s and fees are reported
+ *
+ * @return - A single row of the output file
+ */
+func (bs *blockStats) output(i, class int) string {
+	n := float64(bs.numBlocks)
+	minRate := 0.0
+	if bs.numNonEmpty > 0 {
+		minRate = bs.totalMinRate / float64(bs.numNonEmpty)
+	}
+	return fmt.Sprintf("%d | %f | %f | %f | %f | %f | %f | %f | %f | %f | %f\n",
+		i,
+		bs.totalInterval/n,
+		bs.totalFill/n,
+		float64(bs.numFull)/n,
+		float64(bs.totalTxns[class])/n,
+		bs.totalFees[class]/n,
+		minRate,
+		float64(bs.numSPV)/n,
+		bs.totalMaxSize/n,
+		bs.totalSize/n,
+		bs.totalPenalty/n)
+}
@@ -0,0 +1,63 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestBlockStatsFileExtension(t *testing.T) {
+	expectedExtension := "bsl-dat"
+
+	bsl := newBlockStatsLogger("", 1)
+
+	if bsl.FileExtension() != expectedExtension {
+		t.Error("Expected file extension", expectedExtension, ", got", bsl.FileExtension())
+	}
+}
+
+func TestBlockStatsOutput(t *testing.T) {
+	expectedOutput := "0 | 500.000000 | 0.750000 | 0.500000 | 3.000000 | 2619.000000 | 1.000000 | 0.000000 | 3492.000000 | 2619.000000 | 0.000000\n" +
+		"2 | 100.000000 | 0.000000 | 0.000000 | 0.000000 | 0.000000 | 0.000000 | 1.000000 | 3492.000000 | 0.000000 | 0.000000\n"
+
+	maxSize := 4 * BITCOIN_TRANSACTION_SIZE
+	bsl := newBlockStatsLogger("", 1)
+	pool := newMempool()
+
+	// Same height in two iterations, one full and one half full, then an SPV
+	// block without a minimum fee rate
+	bsl.LogBlock(block{0, 400.0, 400.0, maxSize, 4 * BITCOIN_TRANSACTION_SIZE, 4, newTestTxns(4), 0, false, 0.0}, pool)
+	bsl.LogBlock(block{0, 600.0, 600.0, maxSize, 2 * BITCOIN_TRANSACTION_SIZE, 2, newTestTxns(2), 0, false, 0.0}, pool)
+	bsl.LogBlock(block{2, 100.0, 100.0, maxSize, 0.0, 0, nil, 0, true, 0.0}, pool)
+
+	output := bsl.Outputs()[0]
+	if output != expectedOutput {
+		t.Error("Expected output '", expectedOutput, "', got '", output, "'")
+	}
+
+	bsl.Reset()
+	if len(bsl.heights) != 0 {
+		t.Error("Expected reset to clear heights, got", len(bsl.heights))
+	}
+}
+
+func TestBlockStatsByClass(t *testing.T) {
+	bsl := newBlockStatsLogger("", 2)
+	txns := []txn{
+		txn{class: 0, size: 200.0, feeRate: 2.0},
+		txn{class: 1, size: 500.0, feeRate: 10.0},
+		txn{class: 1, size: 300.0, feeRate: 10.0},
+	}
+	bsl.LogBlock(block{0, 600.0, 600.0, 1000.0, 1000.0, 3, txns, 0, false, 0.0}, newMempool())
+
+	outputs := bsl.Outputs()
+	if len(outputs) != 2 {
+		t.Fatal("Expected an output per class, got", len(outputs))
+	}
+	// The minimum fee rate is that of the block, whatever the class
+	expectedOutputs := []string{
+		"0 | 600.000000 | 1.000000 | 1.000000 | 1.000000 | 400.000000 | 2.000000 | 0.000000 | 1000.000000 | 1000.000000 | 0.000000\n",
+		"0 | 600.000000 | 1.000000 | 1.000000 | 2.000000 | 8000.000000 | 2.000000 | 0.000000 | 1000.000000 | 1000.000000 | 0.000000\n",
+	}
+	for i, expected := range expectedOutputs {
+		if outputs[i] != expected {
+			t.Error("Expected class", i, "output '", expected, "', got '", outputs[i], "'")
+		}
+	}
+}
@@ -10,12 +10,32 @@ const DEFAULT_BLOCK_SIZE float64 = 1024 * 1024
 const DEFAULT_NUM_BLOCKS = 1008    // One week of mining
 const DEFAULT_NUM_ITERATIONS = 100 // Nice sample size
 
+// Seeds of consecutive iterations are this far apart
+const ITERATION_SEED_STRIDE = 7919
+
+// Adaptive stopping parameters
+const DEFAULT_CONVERGENCE_QUANTILE = 0.95 // Track p95 confirmation times
+const MIN_ADAPTIVE_ITERATIONS = 10        // Minimum samples before trusting the CI
+const CONFIDENCE_Z = 1.96                 // 95% confidence interval
+
 // Bucketing parameters for output
 const NEGATIVE_ORDERS = 1
 const POSITIVE_ORDERS = 10
 const NUM_BUCKETS_PER_ORDER = 1000
 const NUM_BUCKETS = (NUM_BUCKETS_PER_ORDER * (POSITIVE_ORDERS + NEGATIVE_ORDERS))
 
+// Time series parameters
+const DEFAULT_SECS_PER_BUCKET float64 = 60.0 // One minute of txn arrivals
+const TIME_SERIES_BUCKETS_PER_ORDER = 100    // Resolution of per bucket quantiles
+
+// Steady state detection parameters
+const MSER_BATCH_SIZE = 5            // MSER-5 batches the backlog of 5 blocks
+const PILOT_SEED_SALT = 0x70696c6f74 // Separates the pilot iteration from the others
+
+// Trace parameters
+const DEFAULT_TRACE_SAMPLE_RATE = 0.01 // Keep 1% of txns
+const TRACE_SEED_SALT = 0x7472616365   // Separates trace sampling from the txns and blocks
+
 // Error handling
 func check(e error) {
 	if e != nil {
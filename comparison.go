@@ -0,0 +1,263 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+/**
+ * `QuantileDifference`
+ *
+ * The difference `other - base` of a confirmation time quantile between two
+ * simulations.  `Base` and `Other` are the quantiles of all confirmed `txn`s,
+ * `Mean` and `HalfWidth` describe the confidence interval of the mean paired
+ * difference over iterations that share a seed.  `KSDistance` and `KSPValue`
+ * are a two-sample Kolmogorov-Smirnov test of the per iteration quantiles.
+ */
+type QuantileDifference struct {
+	Quantile   float64
+	Base       float64
+	Other      float64
+	Mean       float64
+	HalfWidth  float64
+	Pairs      int
+	KSDistance float64
+	KSPValue   float64
+}
+
+/**
+ * @return - Whether the confidence interval of the difference excludes 0
+ */
+func (qd QuantileDifference) Significant() bool {
+	return math.Abs(qd.Mean) > qd.HalfWidth
+}
+
+/**
+ * `SpikeComparison`
+ *
+ * Compares the confirmation times of a single spike between two simulations,
+ * with quantile differences and the Kolmogorov-Smirnov distance between the
+ * confirmation times of all confirmed `txn`s.  The distance is descriptive
+ * only, since `txn`s confirmed by the same block are not independent.
+ */
+type SpikeComparison struct {
+	Class      string
+	Spike      Spike
+	Quantiles  []QuantileDifference
+	KSDistance float64
+}
+
+/**
+ * Compares the `Results` of two simulations of the same `SpikeProfile`.
+ * Iterations are paired by index, so the simulations should share a seed for
+ * the pairs to use common random numbers.
+ *
+ * @param base - The `Results` of the baseline simulation
+ * @param other - The `Results` of the compared simulation
+ *
+ * @return - The comparison of each spike, or an error if the spikes differ
+ */
+func CompareResults(base, other *Results) ([]SpikeComparison, error) {
+	if len(base.Spikes) != len(other.Spikes) {
+		return nil, fmt.Errorf("cannot compare %d spikes to %d spikes", len(base.Spikes), len(other.Spikes))
+	}
+
+	comparisons := make([]SpikeComparison, len(base.Spikes))
+	for i := range base.Spikes {
+		b, o := base.Spikes[i], other.Spikes[i]
+		if b.Class != o.Class {
+			return nil, fmt.Errorf("cannot compare spike %d of class %q to class %q", i, b.Class, o.Class)
+		}
+		baseSamples, otherSamples := base.IterationQuantiles(i), other.IterationQuantiles(i)
+
+		comparisons[i] = SpikeComparison{
+			Class: b.Class,
+			Spike: b.Spike,
+			Quantiles: []QuantileDifference{
+				quantileDifference(0.5, b.Median, o.Median, baseSamples, otherSamples,
+					func(iq IterationQuantiles) float64 { return iq.Median }),
+				quantileDifference(0.95, b.P95, o.P95, baseSamples, otherSamples,
+					func(iq IterationQuantiles) float64 { return iq.P95 }),
+				quantileDifference(0.99, b.P99, o.P99, baseSamples, otherSamples,
+					func(iq IterationQuantiles) float64 { return iq.P99 }),
+			},
+		}
+		comparisons[i].KSDistance = ksDistance(base.plots[i], other.plots[i])
+	}
+	return comparisons, nil
+}
+
+/**
+ * Computes the confidence interval of the mean paired difference of a
+ * quantile, skipping iterations in which either spike confirmed no `txn`s,
+ * and tests whether the quantile has the same distribution over iterations.
+ *
+ * @param q - The quantile
+ * @param base - The quantile of all confirmed `txn`s of the baseline
+ * @param other - The quantile of all confirmed `txn`s of the other simulation
+ * @param baseSamples - The per iteration quantiles of the baseline
+ * @param otherSamples - The per iteration quantiles of the other simulation
+ * @param value - Selects the quantile from `IterationQuantiles`
+ *
+ * @return - The `QuantileDifference`, with an infinite half-width if there
+ *           are fewer than 2 pairs
+ */
+func quantileDifference(q, base, other float64, baseSamples, otherSamples []IterationQuantiles,
+	value func(IterationQuantiles) float64) QuantileDifference {
+
+	differences := []float64{}
+	for j := 0; j < len(baseSamples) && j < len(otherSamples); j++ {
+		d := value(otherSamples[j]) - value(baseSamples[j])
+		if !math.IsNaN(d) {
+			differences = append(differences, d)
+		}
+	}
+
+	qd := QuantileDifference{
+		Quantile:  q,
+		Base:      base,
+		Other:     other,
+		HalfWidth: math.Inf(1),
+		Pairs:     len(differences),
+	}
+	qd.KSDistance, qd.KSPValue = ksTest(iterationValues(baseSamples, value), iterationValues(otherSamples, value))
+	if qd.Pairs == 0 {
+		qd.Mean = math.NaN()
+		return qd
+	}
+
+	for _, d := range differences {
+		qd.Mean += d
+	}
+	qd.Mean /= float64(qd.Pairs)
+
+	if qd.Pairs < 2 {
+		return qd
+	}
+
+	variance := 0.0
+	for _, d := range differences {
+		variance += (d - qd.Mean) * (d - qd.Mean)
+	}
+	variance /= float64(qd.Pairs - 1)
+	qd.HalfWidth = CONFIDENCE_Z * math.Sqrt(variance/float64(qd.Pairs))
+
+	return qd
+}
+
+/**
+ * @param samples - The per iteration quantiles
+ * @param value - Selects the quantile from `IterationQuantiles`
+ *
+ * @return - The quantile of each iteration that confirmed `txn`s
+ */
+func iterationValues(samples []IterationQuantiles, value func(IterationQuantiles) float64) []float64 {
+	values := []float64{}
+	for _, sample := range samples {
+		if v := value(sample); !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+/**
+ * Performs a two-sample Kolmogorov-Smirnov test.  The p-value uses the
+ * asymptotic Kolmogorov distribution, which assumes independent samples, so
+ * each sample should summarize a whole iteration.  Iterations sharing a seed
+ * are positively correlated, which only makes the test conservative.
+ *
+ * @param a - The first sample
+ * @param b - The second sample
+ *
+ * @return - The KS distance and p-value, NaN if either sample is empty
+ */
+func ksTest(a, b []float64) (distance, pValue float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return math.NaN(), math.NaN()
+	}
+
+	a, b = append([]float64{}, a...), append([]float64{}, b...)
+	sort.Float64s(a)
+	sort.Float64s(b)
+
+	// Step both empirical CDFs past every distinct value
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		x := math.Min(a[i], b[j])
+		for i < len(a) && a[i] == x {
+			i++
+		}
+		for j < len(b) && b[j] == x {
+			j++
+		}
+		distance = math.Max(distance, math.Abs(float64(i)/float64(len(a))-float64(j)/float64(len(b))))
+	}
+
+	n := float64(len(a)) * float64(len(b)) / float64(len(a)+len(b))
+	pValue = kolmogorovQ((math.Sqrt(n) + 0.12 + 0.11/math.Sqrt(n)) * distance)
+
+	return
+}
+
+/**
+ * Computes the Kolmogorov-Smirnov distance between the confirmation times of
+ * two plots, at the resolution of the cumulative buckets.
+ *
+ * @param a - The first `cumulativePlot`
+ * @param b - The second `cumulativePlot`
+ *
+ * @return - The largest absolute difference between the CDFs, NaN if either
+ *           plot is empty
+ */
+func ksDistance(a, b *cumulativePlot) (distance float64) {
+	if a.txnCount == 0 || b.txnCount == 0 {
+		return math.NaN()
+	}
+
+	start, end := a.smallestBucket, a.largestBucket
+	if b.smallestBucket < start {
+		start = b.smallestBucket
+	}
+	if b.largestBucket > end {
+		end = b.largestBucket
+	}
+
+	cumulativeA, cumulativeB := int64(0), int64(0)
+	for i := start; i <= end; i++ {
+		cumulativeA += a.buckets[i]
+		cumulativeB += b.buckets[i]
+		d := math.Abs(float64(cumulativeA)/float64(a.txnCount) - float64(cumulativeB)/float64(b.txnCount))
+		distance = math.Max(distance, d)
+	}
+
+	return
+}
+
+/**
+ * Evaluates the survival function of the Kolmogorov distribution,
+ * Q(x) = 2 * sum_{k>=1} (-1)^(k-1) * exp(-2 k^2 x^2)
+ *
+ * @param x - The scaled KS distance
+ *
+ * @return - The probability of a distance of at least `x` under the null
+ *           hypothesis
+ */
+func kolmogorovQ(x float64) float64 {
+	if x < 0.2 {
+		return 1.0
+	}
+
+	sum := 0.0
+	sign := 1.0
+	for k := 1.0; k <= 100; k++ {
+		term := sign * math.Exp(-2*k*k*x*x)
+		sum += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0.0, math.Min(1.0, 2*sum))
+}
@@ -0,0 +1,118 @@
+package bitcoin_load_spike
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKolmogorovQ(t *testing.T) {
+	// The 5% critical value of the Kolmogorov distribution
+	if q := kolmogorovQ(1.358); math.Abs(q-0.05) > 0.001 {
+		t.Error("Expected Q(1.358) = 0.05, got", q)
+	}
+	if q := kolmogorovQ(0.1); q != 1.0 {
+		t.Error("Expected Q(0.1) = 1, got", q)
+	}
+}
+
+func TestKSTest(t *testing.T) {
+	fast := []float64{1, 2, 2, 3, 4}
+	slow := []float64{10, 11, 12, 13, 14, 15}
+
+	if d, p := ksTest(fast, fast); d != 0.0 || p != 1.0 {
+		t.Error("Expected identical samples to have distance 0 and p-value 1, got", d, p)
+	}
+	if d, p := ksTest(fast, slow); d != 1.0 || p > 0.01 {
+		t.Error("Expected disjoint samples to have distance 1 and a small p-value, got", d, p)
+	}
+	// Ties are stepped past together: at 2 the CDFs are 0.6 and 1
+	if d, _ := ksTest(fast, []float64{2, 2, 2, 2}); math.Abs(d-0.4) > 1e-9 {
+		t.Error("Expected distance 0.4, got", d)
+	}
+	if d, _ := ksTest(fast, nil); !math.IsNaN(d) {
+		t.Error("Expected NaN distance for an empty sample, got", d)
+	}
+}
+
+func TestKSDistancePlots(t *testing.T) {
+	fast, slow := newCumulativePlot(), newCumulativePlot()
+	for i := 0; i < 100; i++ {
+		fast.incrementBucket(confirmationBucket(float64(10 + i)))
+		slow.incrementBucket(confirmationBucket(float64(1000 + i)))
+	}
+
+	if d := ksDistance(fast, fast); d != 0.0 {
+		t.Error("Expected identical plots to have distance 0, got", d)
+	}
+	if d := ksDistance(fast, slow); d != 1.0 {
+		t.Error("Expected disjoint plots to have distance 1, got", d)
+	}
+	if d := ksDistance(fast, newCumulativePlot()); !math.IsNaN(d) {
+		t.Error("Expected NaN distance for an empty plot, got", d)
+	}
+}
+
+func TestQuantileDifference(t *testing.T) {
+	median := func(iq IterationQuantiles) float64 { return iq.Median }
+	base := []IterationQuantiles{{10, 0, 0}, {20, 0, 0}, {math.NaN(), 0, 0}, {30, 0, 0}}
+	other := []IterationQuantiles{{12, 0, 0}, {24, 0, 0}, {5, 0, 0}}
+
+	// The third iteration has no base sample, the fourth has no pair
+	qd := quantileDifference(0.5, 20, 24, base, other, median)
+	if qd.Pairs != 2 || qd.Mean != 3.0 {
+		t.Error("Expected 2 pairs with a mean difference of 3, got", qd.Pairs, qd.Mean)
+	}
+	expected := CONFIDENCE_Z * math.Sqrt(2.0/2.0)
+	if math.Abs(qd.HalfWidth-expected) > 1e-9 || !qd.Significant() {
+		t.Error("Expected a significant difference with half-width", expected, ", got", qd.HalfWidth)
+	}
+	// The KS test uses every iteration with a quantile: 10, 20, 30 and 12, 24, 5
+	if math.Abs(qd.KSDistance-1.0/3.0) > 1e-9 {
+		t.Error("Expected KS distance 1/3 between the iteration medians, got", qd.KSDistance)
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	sp := &SpikeProfile{
+		[]Spike{
+			Spike{0.0, 0.5},
+			Spike{0.5, 2.0},
+		},
+	}
+	simulate := func(blockSize float64) *Results {
+		return NewLoadSpikeSimulation(blockSize, int64(40), int64(4)).
+			UseSpikeProfile(sp).
+			UseSeed(7).
+			Simulate()
+	}
+	base := simulate(DEFAULT_BLOCK_SIZE)
+
+	// A simulation compared to itself with the same seed has no differences
+	comparisons, err := CompareResults(base, simulate(DEFAULT_BLOCK_SIZE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, qd := range comparisons[0].Quantiles {
+		if qd.Pairs != 4 || qd.Mean != 0.0 || qd.HalfWidth != 0.0 {
+			t.Error("Expected no difference with the same seed, got", qd)
+		}
+	}
+	if comparisons[0].KSDistance != 0.0 {
+		t.Error("Expected KS distance 0 with the same seed, got", comparisons[0].KSDistance)
+	}
+
+	// Larger blocks clear the spike's backlog faster
+	comparisons, err = CompareResults(base, simulate(4*DEFAULT_BLOCK_SIZE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p95 := comparisons[1].Quantiles[1]
+	if p95.Mean >= 0.0 || !p95.Significant() {
+		t.Error("Expected larger blocks to significantly reduce the spike's p95, got", p95)
+	}
+
+	other := &Results{Spikes: []SpikeResults{{}}}
+	if _, err := CompareResults(base, other); err == nil {
+		t.Error("Expected an error comparing different spike profiles")
+	}
+}
@@ -0,0 +1,342 @@
+package bitcoin_load_spike
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+/**
+ * `Config`
+ *
+ * Describes a complete simulation: its parameters, `SpikeProfile`, loggers
+ * and output files.  A `Config` can be stored as JSON, fields missing from
+ * the JSON keep their default values.  If `TxnClasses` are given they replace
+ * the `SpikeProfile`, and `Miners` replace the single fee maximizing miner.
+ * `SPVMining`, `Propagation` and the `Penalty` are disabled unless set, and
+ * the `BlockSizePolicy` keeps the `BlockSize` unless set.
+ */
+type Config struct {
+	BlockSize        float64                `json:"block_size"`
+	NumBlocks        int64                  `json:"num_blocks"`
+	NumIterations    int64                  `json:"num_iterations"`
+	Seed             int64                  `json:"seed"`
+	SpikeProfile     SpikeProfile           `json:"spike_profile"`
+	TxnClasses       []TxnClass             `json:"txn_classes,omitempty"`
+	Miners           []Miner                `json:"miners,omitempty"`
+	SPVMining        *SPVMining             `json:"spv_mining,omitempty"`
+	Propagation      *Propagation           `json:"propagation,omitempty"`
+	BlockSizePolicy  *BlockSizePolicyConfig `json:"block_size_policy,omitempty"`
+	Penalty          *Penalty               `json:"penalty,omitempty"`
+	AdaptiveStopping AdaptiveStoppingConfig `json:"adaptive_stopping"`
+	WarmUp           WarmUpConfig           `json:"warm_up"`
+	Loggers          LoggersConfig          `json:"loggers"`
+}
+
+/**
+ * Parameters of adaptive stopping, disabled unless `Precision` or `Timeout`
+ * is set.  `Timeout` is a duration such as "30m".
+ */
+type AdaptiveStoppingConfig struct {
+	Quantile  float64 `json:"quantile"`
+	Precision float64 `json:"precision"`
+	Timeout   string  `json:"timeout"`
+}
+
+/**
+ * Parameters of the warm-up period.
+ */
+type WarmUpConfig struct {
+	Blocks  int64   `json:"blocks"`
+	Seconds float64 `json:"seconds"`
+	MSER    bool    `json:"mser"`
+}
+
+/**
+ * Selects the loggers of the simulation.  Every logger writes its files with
+ * the `FilePrefix`, except for the trace which is written to `Trace`.
+ */
+type LoggersConfig struct {
+	FilePrefix    string  `json:"file_prefix"`
+	Cumulative    bool    `json:"cumulative"`
+	KaplanMeier   bool    `json:"kaplan_meier"`
+	Analytical    bool    `json:"analytical"`
+	TimeSeries    bool    `json:"time_series"`
+	Backlog       bool    `json:"backlog"`
+	SecsPerBucket float64 `json:"secs_per_bucket"`
+	BlockStats    bool    `json:"block_stats"`
+	Depths        []int64 `json:"depths"`
+	Payments      bool    `json:"payments"`
+	FeeEstimates  bool    `json:"fee_estimates"`
+	Trace         string  `json:"trace"`
+	TraceRate     float64 `json:"trace_rate"`
+}
+
+/**
+ * Returns the default `Config`, which runs the 10x spike profile with the
+ * `CumulativeLogger`.
+ *
+ * @return - The default `Config`
+ */
+func DefaultConfig() *Config {
+	return &Config{
+		BlockSize:     DEFAULT_BLOCK_SIZE,
+		NumBlocks:     DEFAULT_NUM_BLOCKS,
+		NumIterations: DEFAULT_NUM_ITERATIONS,
+		SpikeProfile: SpikeProfile{
+			[]Spike{
+				Spike{0.0, 0.1},
+				Spike{0.33, 10.0},
+				Spike{0.67, 0.11},
+			},
+		},
+		AdaptiveStopping: AdaptiveStoppingConfig{
+			Quantile: DEFAULT_CONVERGENCE_QUANTILE,
+		},
+		Loggers: LoggersConfig{
+			FilePrefix:    "data/load-spike",
+			Cumulative:    true,
+			SecsPerBucket: DEFAULT_SECS_PER_BUCKET,
+			TraceRate:     DEFAULT_TRACE_SAMPLE_RATE,
+		},
+	}
+}
+
+/**
+ * Reads a JSON `Config` from `path` on top of `DefaultConfig`.
+ *
+ * @param path - The path of the JSON file
+ *
+ * @return - The `Config` and any error reading or parsing the file
+ */
+func LoadConfig(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := DefaultConfig()
+	if err := json.Unmarshal(contents, config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return config, nil
+}
+
+/**
+ * @return - The `Config` as indented JSON
+ */
+func (c *Config) JSON() string {
+	contents, err := json.MarshalIndent(c, "", "  ")
+	check(err)
+	return string(contents)
+}
+
+/**
+ * Uses a constant `load` of the default class for the entire simulation.
+ *
+ * @param load - The percentage of `BITCOIN_MAX_TPS`
+ */
+func (c *Config) UseConstantLoad(load float64) {
+	c.SpikeProfile = SpikeProfile{[]Spike{Spike{0.0, load}}}
+	c.TxnClasses = nil
+}
+
+/**
+ * Verifies that the `Config` describes a valid simulation.
+ *
+ * @return - nil if the `Config` is valid, otherwise the first problem
+ */
+func (c *Config) Validate() error {
+	if c.BlockSize < BITCOIN_TRANSACTION_SIZE {
+		return fmt.Errorf("block size %f cannot fit a single txn", c.BlockSize)
+	}
+	if c.NumBlocks < 1 || c.NumIterations < 1 {
+		return errors.New("number of blocks and iterations must be positive")
+	}
+	if len(c.TxnClasses) > 0 {
+		if err := ValidateTxnClasses(c.TxnClasses); err != nil {
+			return err
+		}
+	} else if err := c.SpikeProfile.Validate(); err != nil {
+		return err
+	}
+	for _, class := range c.TxnClasses {
+		if err := class.fits(c.BlockSize); err != nil {
+			return err
+		}
+	}
+	if len(c.Miners) > 0 {
+		if err := ValidateMiners(c.Miners); err != nil {
+			return err
+		}
+	}
+	if c.SPVMining != nil {
+		if err := c.SPVMining.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Propagation != nil {
+		if err := c.Propagation.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.BlockSizePolicy != nil {
+		if err := c.BlockSizePolicy.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Penalty != nil {
+		if err := c.Penalty.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := c.timeout(); err != nil {
+		return err
+	}
+	if c.AdaptiveStopping.Quantile <= 0.0 || c.AdaptiveStopping.Quantile > 1.0 {
+		return fmt.Errorf("adaptive stopping quantile %f is not in (0, 1]", c.AdaptiveStopping.Quantile)
+	}
+	if c.WarmUp.Blocks < 0 || c.WarmUp.Blocks > c.NumBlocks || c.WarmUp.Seconds < 0.0 {
+		return errors.New("invalid warm-up period")
+	}
+	if c.Loggers.SecsPerBucket <= 0.0 {
+		return errors.New("seconds per bucket must be positive")
+	}
+	if c.Loggers.Trace != "" && (c.Loggers.TraceRate <= 0.0 || c.Loggers.TraceRate > 1.0) {
+		return fmt.Errorf("trace rate %f is not in (0, 1]", c.Loggers.TraceRate)
+	}
+	if c.Loggers.Analytical {
+		sp, err := analyticalProfile(c.classes())
+		if err != nil {
+			return err
+		}
+		if _, err := newAnalyticalLogger("", c.BlockSize, sp); err != nil {
+			return err
+		}
+		if err := analyticalMining(c.Miners, c.SPVMining, c.Propagation); err != nil {
+			return err
+		}
+		if (c.BlockSizePolicy != nil && c.BlockSizePolicy.Kind != BLOCK_SIZE_FIXED) || c.Penalty != nil {
+			return errors.New("analytical baseline requires a fixed block size")
+		}
+	}
+	if c.Loggers.FeeEstimates && !useFeeEstimates(c.TxnClasses) {
+		return errors.New("fee estimate logging requires a transaction class with a fee target")
+	}
+	for _, depth := range c.Loggers.Depths {
+		if depth < 1 {
+			return fmt.Errorf("confirmation depth %d is less than 1", depth)
+		}
+	}
+	return nil
+}
+
+/**
+ * Builds the `LoadSpikeSimulation` described by the `Config`.
+ *
+ * @return - The new `LoadSpikeSimulation` and any validation error
+ */
+func (c *Config) Simulation() (*LoadSpikeSimulation, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	lss := NewLoadSpikeSimulation(c.BlockSize, c.NumBlocks, c.NumIterations).
+		UseWarmUp(c.WarmUp.Blocks, c.WarmUp.Seconds)
+	if len(c.TxnClasses) > 0 {
+		lss.UseTxnClasses(c.classes())
+	} else {
+		lss.UseSpikeProfile(&c.classes()[0].SpikeProfile)
+	}
+	if len(c.Miners) > 0 {
+		lss.UseMiners(c.Miners)
+	}
+	if c.SPVMining != nil {
+		lss.UseSPVMining(c.SPVMining.Window, c.SPVMining.Probability)
+	}
+	if c.Propagation != nil {
+		lss.UsePropagation(c.Propagation.Latency, c.Propagation.SecondsPerMB)
+	}
+	if c.BlockSizePolicy != nil {
+		lss.UseBlockSizePolicy(c.BlockSizePolicy.Policy(c.BlockSize, c.Miners))
+	}
+	if c.Penalty != nil {
+		lss.UsePenalty(c.Penalty.Kind, c.Penalty.Reward, c.Penalty.MaxFactor)
+	}
+
+	if c.Seed != 0 {
+		lss.UseSeed(c.Seed)
+	}
+	if c.WarmUp.MSER {
+		lss.UseSteadyStateDetection()
+	}
+
+	timeout, _ := c.timeout()
+	if c.AdaptiveStopping.Precision > 0.0 || timeout > 0 {
+		lss.UseAdaptiveStopping(c.AdaptiveStopping.Quantile, c.AdaptiveStopping.Precision, timeout)
+	}
+
+	l := c.Loggers
+	if l.Cumulative {
+		lss.AddCumulativeLogger(l.FilePrefix)
+	}
+	if l.KaplanMeier {
+		lss.AddKaplanMeierLogger(l.FilePrefix)
+	}
+	if l.Analytical {
+		lss.AddAnalyticalLogger(l.FilePrefix)
+	}
+	if l.TimeSeries {
+		lss.AddTimeSeriesLogger(l.FilePrefix, l.SecsPerBucket)
+	}
+	if l.Backlog {
+		lss.AddBacklogLogger(l.FilePrefix, l.SecsPerBucket)
+	}
+	if l.BlockStats {
+		lss.AddBlockStatsLogger(l.FilePrefix)
+	}
+	if len(l.Depths) > 0 {
+		lss.AddConfirmationDepthLogger(l.FilePrefix, l.Depths)
+	}
+	if l.Payments {
+		lss.AddPaymentLogger(l.FilePrefix)
+	}
+	if l.FeeEstimates {
+		lss.AddFeeEstimateLogger(l.FilePrefix)
+	}
+	if l.Trace != "" {
+		lss.AddTraceLogger(l.Trace, l.TraceRate)
+	}
+
+	return lss, nil
+}
+
+/**
+ * Copies the `TxnClasses`, or the default class of the `SpikeProfile` if no
+ * classes are given, so the simulation does not share the `Config`'s spikes.
+ *
+ * @return - The `TxnClass`es of the simulation
+ */
+func (c *Config) classes() []TxnClass {
+	if len(c.TxnClasses) == 0 {
+		return []TxnClass{defaultTxnClass(&SpikeProfile{append([]Spike{}, c.SpikeProfile.Spikes...)})}
+	}
+
+	classes := append([]TxnClass{}, c.TxnClasses...)
+	for i := range classes {
+		classes[i].SpikeProfile.Spikes = append([]Spike{}, classes[i].SpikeProfile.Spikes...)
+	}
+	return classes
+}
+
+/**
+ * @return - The adaptive stopping timeout, 0 if not set
+ */
+func (c *Config) timeout() (time.Duration, error) {
+	if c.AdaptiveStopping.Timeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.AdaptiveStopping.Timeout)
+}
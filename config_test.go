@@ -0,0 +1,171 @@
+package bitcoin_load_spike
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	config := DefaultConfig()
+	if err := config.Validate(); err != nil {
+		t.Error("Expected default config to be valid, got", err)
+	}
+
+	sim, err := config.Simulation()
+	if err != nil {
+		t.Fatal("Expected simulation from default config, got", err)
+	}
+	if sim.numBlocks != DEFAULT_NUM_BLOCKS || len(sim.loggers) != 1 {
+		t.Error("Expected", DEFAULT_NUM_BLOCKS, "blocks and 1 logger, got", sim.numBlocks, "and", len(sim.loggers))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	contents := `{
+		"num_blocks": 50,
+		"spike_profile": {"spikes": [{"percent": 0, "load": 0.5}]},
+		"loggers": {"backlog": true, "depths": [3, 6]}
+	}`
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal("Expected config to load, got", err)
+	}
+	if config.NumBlocks != 50 || config.NumIterations != DEFAULT_NUM_ITERATIONS {
+		t.Error("Expected 50 blocks and default iterations, got", config.NumBlocks, "and", config.NumIterations)
+	}
+	if len(config.SpikeProfile.Spikes) != 1 || config.SpikeProfile.Spikes[0].Load != 0.5 {
+		t.Error("Expected the spike profile to be replaced, got", config.SpikeProfile.Spikes)
+	}
+	if !config.Loggers.Cumulative || !config.Loggers.Backlog {
+		t.Error("Expected cumulative and backlog loggers to be enabled")
+	}
+
+	sim, err := config.Simulation()
+	if err != nil {
+		t.Fatal("Expected simulation from config, got", err)
+	}
+	// Cumulative, backlog and one logger per depth
+	if len(sim.loggers) != 4 {
+		t.Error("Expected 4 loggers, got", len(sim.loggers))
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.SpikeProfile = SpikeProfile{[]Spike{Spike{0.5, 1.0}}}
+	if config.Validate() == nil {
+		t.Error("Expected spike profile not starting at 0 to be invalid")
+	}
+
+	config = DefaultConfig()
+	config.AdaptiveStopping.Timeout = "soon"
+	if config.Validate() == nil {
+		t.Error("Expected unparseable timeout to be invalid")
+	}
+
+	config = DefaultConfig()
+	config.Loggers.Depths = []int64{0}
+	if _, err := config.Simulation(); err == nil {
+		t.Error("Expected confirmation depth 0 to be invalid")
+	}
+}
+
+func TestConfigTxnClasses(t *testing.T) {
+	contents := `{
+		"txn_classes": [
+			{"name": "exchange", "spike_profile": {"spikes": [{"percent": 0, "load": 0.2}]},
+			 "size": {"kind": "lognormal", "median": 400, "sigma": 0.3},
+			 "fee_rate": {"kind": "exponential", "mean": 10}},
+			{"name": "payments", "spike_profile": {"spikes": [{"percent": 0, "load": 0.1}, {"percent": 0.5, "load": 1.0}]},
+			 "size": {"kind": "constant", "value": 250},
+			 "fee_rate": {"kind": "uniform", "min": 1, "max": 5}}
+		]
+	}`
+	config := DefaultConfig()
+	if err := json.Unmarshal([]byte(contents), config); err != nil {
+		t.Fatal(err)
+	}
+
+	sim, err := config.Simulation()
+	if err != nil {
+		t.Fatal("Expected simulation from config, got", err)
+	}
+	if len(sim.classes) != 2 || len(sim.spikes) != 3 || sim.spikes[2].name != "payments" {
+		t.Error("Expected 3 spikes of 2 classes, got", sim.spikes)
+	}
+
+	config.Loggers.Analytical = true
+	if config.Validate() == nil {
+		t.Error("Expected analytical baseline of txn classes to be invalid")
+	}
+
+	config.Loggers.Analytical = false
+	config.Miners = []Miner{Miner{Name: "pool", Hashrate: 1.0, Policy: TEMPLATE_EMPTY}}
+	if sim, err := config.Simulation(); err != nil || len(sim.miners) != 1 || sim.miners[0].Name != "pool" {
+		t.Error("Expected the miners of the config, got", err)
+	}
+	config.Miners[0].Policy = "lifo"
+	if config.Validate() == nil {
+		t.Error("Expected a miner with an unknown policy to be invalid")
+	}
+
+	config.Miners = nil
+	config.SPVMining = &SPVMining{Window: 30.0, Probability: 2.0}
+	if config.Validate() == nil {
+		t.Error("Expected an SPV mining probability above 1 to be invalid")
+	}
+
+	config.SPVMining = nil
+	config.Propagation = &Propagation{Latency: 2.0, SecondsPerMB: 10.0}
+	if sim, err := config.Simulation(); err != nil || sim.propagation == nil {
+		t.Error("Expected the propagation delay of the config, got", err)
+	}
+	config.Loggers.Analytical = true
+	if config.Validate() == nil {
+		t.Error("Expected analytical baseline with propagation delays to be invalid")
+	}
+
+	config.Loggers.Analytical = false
+	config.Propagation = nil
+	config.BlockSizePolicy = &BlockSizePolicyConfig{Kind: BLOCK_SIZE_MEDIAN, Window: 144, Multiplier: 2.0}
+	if sim, err := config.Simulation(); err != nil || sim.sizePolicy == nil {
+		t.Error("Expected the block size policy of the config, got", err)
+	}
+	config.BlockSizePolicy.Window = 0
+	if config.Validate() == nil {
+		t.Error("Expected a median block size without a window to be invalid")
+	}
+
+	config.BlockSizePolicy = nil
+	config.Penalty = &Penalty{Reward: 1.25e9, MaxFactor: 0.5}
+	if config.Validate() == nil {
+		t.Error("Expected a penalty maximum factor below 1 to be invalid")
+	}
+	config.Penalty.MaxFactor = 2.0
+	config.Penalty.Kind = "cubic"
+	if config.Validate() == nil {
+		t.Error("Expected an unknown penalty kind to be invalid")
+	}
+	config.Penalty.Kind = PENALTY_LINEAR
+	if sim, err := config.Simulation(); err != nil || sim.penalty == nil || sim.penalty.Kind != PENALTY_LINEAR {
+		t.Error("Expected the penalty of the config, got", err)
+	}
+
+	config.Penalty = nil
+	config.TxnClasses[1].Size.Value = 2 * config.BlockSize
+	if config.Validate() == nil {
+		t.Error("Expected txns larger than a block to be invalid")
+	}
+
+	config.UseConstantLoad(0.5)
+	if config.TxnClasses != nil || config.Validate() != nil {
+		t.Error("Expected a constant load to replace the txn classes")
+	}
+}
@@ -0,0 +1,118 @@
+package bitcoin_load_spike
+
+import "fmt"
+
+/**
+ * `ConfirmationDepthLogger`
+ *
+ * Records the time from a `txn`s arrival until it has `depth` confirmations,
+ * i.e. until the `depth`-th block counting the block that included it.  The
+ * confirmation times are stored in a `CumulativeLogger`, so the output has the
+ * same format as the cumulative output.  `txn`s without `depth` confirmations
+ * at the end of an iteration are counted as censored.
+ */
+type ConfirmationDepthLogger struct {
+	cumulative *CumulativeLogger
+	depth      int64
+	pending    []txn
+	included   [][]txn
+}
+
+/**
+ * Initializes a new `ConfirmationDepthLogger`
+ *
+ * @param cl - The `CumulativeLogger` that records the confirmation times
+ * @param depth - The number of confirmations to wait for
+ *
+ * @return - The new `ConfirmationDepthLogger`
+ */
+func newConfirmationDepthLogger(cl *CumulativeLogger, depth int64) *ConfirmationDepthLogger {
+	return &ConfirmationDepthLogger{
+		cumulative: cl,
+		depth:      depth,
+		pending:    []txn{},
+		included:   make([][]txn, depth),
+	}
+}
+
+/**
+ * @return - The specified prefix for the output file.
+ */
+func (cdl ConfirmationDepthLogger) FilePrefix() string {
+	return cdl.cumulative.FilePrefix()
+}
+
+/**
+ * @return - The file extension for `ConfirmationDepthLogger` output
+ */
+func (cdl ConfirmationDepthLogger) FileExtension() string {
+	return cdl.cumulative.FileExtension()
+}
+
+/**
+ * Holds a newly included `txn` until its block is mined.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (cdl *ConfirmationDepthLogger) Log(blockTimestamp float64, t txn) {
+	cdl.pending = append(cdl.pending, t)
+}
+
+/**
+ * Stores the `txn`s included by the block and records the `txn`s that reach
+ * `depth` confirmations with this block.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (cdl *ConfirmationDepthLogger) LogBlock(b block, pool *mempool) {
+	cdl.included[b.height%cdl.depth] = cdl.pending
+	cdl.pending = []txn{}
+
+	// Txns included `depth - 1` blocks ago now have `depth` confirmations
+	slot := (b.height + 1) % cdl.depth
+	for _, t := range cdl.included[slot] {
+		cdl.cumulative.Log(b.timestamp, t)
+	}
+	cdl.included[slot] = nil
+}
+
+/**
+ * Censors the `txn`s that remain in the `mempool` and those included without
+ * reaching `depth` confirmations.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (cdl *ConfirmationDepthLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	shallow := newMempool()
+	for i, txns := range cdl.included {
+		for _, t := range txns {
+			shallow.add(t)
+		}
+		cdl.included[i] = nil
+	}
+
+	cdl.cumulative.EndIteration(endTimestamp, shallow)
+	cdl.cumulative.EndIteration(endTimestamp, pool)
+}
+
+/**
+ * Accumulates the file contents for each spike.
+ *
+ * @return - The file contents for each `Spike` in the `SpikeProfile`
+ */
+func (cdl *ConfirmationDepthLogger) Outputs() []string {
+	fmt.Println("[ConfirmationDepthLogger]: confirmation times for depth", cdl.depth)
+	return cdl.cumulative.Outputs()
+}
+
+/**
+ * Clears the logging state.
+ */
+func (cdl *ConfirmationDepthLogger) Reset() {
+	cdl.cumulative.Reset()
+	cdl.pending = []txn{}
+	cdl.included = make([][]txn, cdl.depth)
+}
@@ -0,0 +1,58 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestConfirmationDepthLogger(t *testing.T) {
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(1)).
+		UseSpikeProfile(&SpikeProfile{[]Spike{Spike{0.0, 0.1}}}).
+		AddConfirmationDepthLogger("prefix", []int64{1, 3})
+
+	if len(sim.loggers) != 2 {
+		t.Fatal("Expected 2 loggers, got", len(sim.loggers))
+	}
+	if sim.loggers[1].FilePrefix() != "prefix-k3" {
+		t.Error("Expected file prefix prefix-k3, got", sim.loggers[1].FilePrefix())
+	}
+
+	k1 := sim.loggers[0].(*ConfirmationDepthLogger)
+	k3 := sim.loggers[1].(*ConfirmationDepthLogger)
+	pool := newMempool()
+
+	// A txn arriving at 0 is included in block 0 at 10s, blocks 1 and 2 are
+	// mined at 100s and 1000s
+	timestamps := []float64{10.0, 100.0, 1000.0}
+	for height, timestamp := range timestamps {
+		for _, logger := range []*ConfirmationDepthLogger{k1, k3} {
+			if height == 0 {
+				logger.Log(timestamp, newTestTxn(0.0, 0))
+			}
+			logger.LogBlock(block{height: int64(height), timestamp: timestamp}, pool)
+		}
+	}
+
+	if k1.cumulative.plots[0].buckets[2000] != 1 {
+		t.Error("Expected txn to have 1 confirmation after 10 seconds")
+	}
+	if k3.cumulative.plots[0].buckets[4000] != 1 {
+		t.Error("Expected txn to have 3 confirmations after 1000 seconds")
+	}
+}
+
+func TestConfirmationDepthLoggerCensoring(t *testing.T) {
+	cl := &CumulativeLogger{[]*cumulativePlot{newCumulativePlot()}, "", false}
+	cdl := newConfirmationDepthLogger(cl, 6)
+
+	cdl.Log(10.0, newTestTxn(0.0, 0))
+	cdl.LogBlock(block{height: 0, timestamp: 10.0}, newMempool())
+
+	pool := newMempool()
+	pool.add(newTestTxn(5.0, 0))
+	cdl.EndIteration(100.0, pool)
+
+	if cl.plots[0].txnCount != 0 {
+		t.Error("Expected no txns with 6 confirmations, got", cl.plots[0].txnCount)
+	}
+	if cl.plots[0].censoredCount != 2 {
+		t.Error("Expected 2 censored txns, got", cl.plots[0].censoredCount)
+	}
+}
@@ -0,0 +1,202 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/**
+ * `convergenceMonitor`
+ *
+ * Tracks a confirmation time quantile for every spike across iterations and
+ * decides when the simulation has gathered enough samples.  Each iteration
+ * contributes one sample per spike with any `txn`s, the mean of the samples
+ * is reported along with the half-width of its confidence interval.  `txn`s
+ * still unconfirmed at the end of an iteration count at their age, a lower
+ * bound on their confirmation time, so a backlog raises the quantile instead
+ * of being left out.  Spikes without load have no `txn`s and are not tracked.
+ */
+type convergenceMonitor struct {
+	quantile    float64
+	precision   float64
+	maxDuration time.Duration
+	tracked     []bool
+	current     []*cumulativePlot
+	samples     [][]float64
+}
+
+/**
+ * Initializes a new `convergenceMonitor` for the spikes with `loads`.
+ *
+ * @param loads - The load of each spike
+ * @param quantile - The confirmation time quantile to track, e.g. 0.95
+ * @param precision - The target relative half-width of the confidence interval
+ * @param maxDuration - The maximum wall clock time to run, 0 for no limit
+ *
+ * @return - The new `convergenceMonitor`
+ */
+func newConvergenceMonitor(loads []float64, quantile, precision float64, maxDuration time.Duration) *convergenceMonitor {
+	cm := &convergenceMonitor{
+		quantile:    quantile,
+		precision:   precision,
+		maxDuration: maxDuration,
+	}
+	cm.tracked = make([]bool, len(loads))
+	for i, load := range loads {
+		cm.tracked[i] = load > 0.0
+	}
+	cm.current = make([]*cumulativePlot, len(loads))
+	cm.samples = make([][]float64, len(loads))
+	cm.Reset()
+
+	return cm
+}
+
+/**
+ * Records the confirmation time of `t` for the current iteration.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (cm *convergenceMonitor) Log(blockTimestamp float64, t txn) {
+	b := confirmationBucket(blockTimestamp - t.time)
+	if b >= NUM_BUCKETS {
+		b = NUM_BUCKETS - 1
+	}
+
+	cm.current[t.index].incrementBucket(b)
+}
+
+/**
+ * Records the `txn`s that remain unconfirmed at the end of the current
+ * iteration, at their age.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (cm *convergenceMonitor) EndIteration(endTimestamp float64, pool *mempool) {
+	for _, t := range pool.txns {
+		b := confirmationBucket(endTimestamp - t.time)
+		if b >= NUM_BUCKETS {
+			b = NUM_BUCKETS - 1
+		}
+
+		cm.current[t.index].censor(b)
+	}
+}
+
+/**
+ * Closes the current iteration, storing a quantile sample for each spike that
+ * recorded at least one confirmed or unconfirmed `txn`.
+ */
+func (cm *convergenceMonitor) endIteration() {
+	for i, plot := range cm.current {
+		if plot.txnCount+plot.censoredCount > 0 {
+			cm.samples[i] = append(cm.samples[i], plot.censoredQuantile(cm.quantile))
+		}
+		cm.current[i] = newCumulativePlot()
+	}
+}
+
+/**
+ * Calculates the mean and confidence interval half-width of the quantile
+ * samples for spike `i`.
+ *
+ * @param i - The spike index
+ *
+ * @return - The mean, the half-width and the number of samples
+ */
+func (cm *convergenceMonitor) estimate(i int) (mean, halfWidth float64, n int) {
+	n = len(cm.samples[i])
+	if n == 0 {
+		return
+	}
+
+	for _, s := range cm.samples[i] {
+		mean += s
+	}
+	mean /= float64(n)
+
+	if n < 2 {
+		halfWidth = math.Inf(1)
+		return
+	}
+
+	variance := 0.0
+	for _, s := range cm.samples[i] {
+		variance += (s - mean) * (s - mean)
+	}
+	variance /= float64(n - 1)
+	halfWidth = CONFIDENCE_Z * math.Sqrt(variance/float64(n))
+
+	return
+}
+
+/**
+ * Checks whether every tracked spike has at least `MIN_ADAPTIVE_ITERATIONS`
+ * samples and has reached the target precision.
+ *
+ * @return - Whether the simulation has converged
+ */
+func (cm *convergenceMonitor) converged() bool {
+	sampled := false
+	for i := range cm.samples {
+		if !cm.tracked[i] {
+			continue
+		}
+		sampled = true
+
+		mean, halfWidth, n := cm.estimate(i)
+		if n < MIN_ADAPTIVE_ITERATIONS || halfWidth > cm.precision*mean {
+			return false
+		}
+	}
+	return sampled
+}
+
+/**
+ * Decides whether the simulation should stop after the current iteration.
+ *
+ * @param elapsed - Wall clock time since the simulation started
+ *
+ * @return - Whether to stop iterating
+ */
+func (cm *convergenceMonitor) shouldStop(elapsed time.Duration) bool {
+	if cm.maxDuration > 0 && elapsed >= cm.maxDuration {
+		return true
+	}
+	return cm.precision > 0 && cm.converged()
+}
+
+/**
+ * Prints the quantile estimate for each spike and the number of iterations
+ * that were performed.
+ *
+ * @param iterations - The number of completed iterations
+ */
+func (cm *convergenceMonitor) PrintSummary(iterations int64) {
+	status := "reached"
+	if !cm.converged() {
+		status = "not reached"
+	}
+
+	fmt.Println("[Convergence]")
+	fmt.Println("     iterations:", iterations)
+	fmt.Println(fmt.Sprintf("     target precision %.2f%% %s", 100*cm.precision, status))
+	for i := range cm.samples {
+		mean, halfWidth, n := cm.estimate(i)
+		fmt.Println(fmt.Sprintf("    spike %d: p%.f = %f +/- %f (%d samples)",
+			i, 100*cm.quantile, mean, halfWidth, n))
+	}
+}
+
+/**
+ * Clears all samples and the current iteration.
+ */
+func (cm *convergenceMonitor) Reset() {
+	for i := range cm.current {
+		cm.current[i] = newCumulativePlot()
+		cm.samples[i] = []float64{}
+	}
+}
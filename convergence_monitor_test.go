@@ -0,0 +1,126 @@
+package bitcoin_load_spike
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQuantile(t *testing.T) {
+	cp := newCumulativePlot()
+	if cp.quantile(0.5) != 0.0 {
+		t.Error("Expected quantile of empty plot to be 0, got", cp.quantile(0.5))
+	}
+
+	// 9 txns confirmed in 10 seconds, 1 in 1000 seconds
+	for i := 0; i < 9; i++ {
+		cp.incrementBucket(2000)
+	}
+	cp.incrementBucket(4000)
+
+	if cp.quantile(0.5) != 10.0 {
+		t.Error("Expected median to be 10, got", cp.quantile(0.5))
+	}
+	if cp.quantile(0.95) != 1000.0 {
+		t.Error("Expected p95 to be 1000, got", cp.quantile(0.95))
+	}
+
+	// 10 more txns still unconfirmed after 100 seconds
+	for i := 0; i < 10; i++ {
+		cp.censor(3000)
+	}
+	if cp.censoredQuantile(0.4) != 10.0 {
+		t.Error("Expected censored p40 to be 10, got", cp.censoredQuantile(0.4))
+	}
+	if cp.censoredQuantile(0.5) != 100.0 {
+		t.Error("Expected censored median to be 100, got", cp.censoredQuantile(0.5))
+	}
+}
+
+func TestConvergenceMonitorEstimate(t *testing.T) {
+	cm := newConvergenceMonitor([]float64{0.5, 0.0}, 0.5, 0.01, 0)
+
+	for i := 0; i < MIN_ADAPTIVE_ITERATIONS; i++ {
+		cm.Log(10.0, newTestTxn(0.0, 0))
+		cm.endIteration()
+	}
+
+	mean, halfWidth, n := cm.estimate(0)
+	if n != MIN_ADAPTIVE_ITERATIONS {
+		t.Error("Expected", MIN_ADAPTIVE_ITERATIONS, "samples, got", n)
+	}
+	if math.Abs(mean-10.0) > 1e-9 {
+		t.Error("Expected mean to be 10, got", mean)
+	}
+	if halfWidth != 0.0 {
+		t.Error("Expected half-width to be 0 for identical samples, got", halfWidth)
+	}
+
+	// Spike 1 has no load and should not block convergence
+	if _, _, n := cm.estimate(1); n != 0 {
+		t.Error("Expected no samples for spike 1, got", n)
+	}
+	if !cm.converged() {
+		t.Error("Expected monitor to have converged")
+	}
+}
+
+func TestConvergenceMonitorShouldStop(t *testing.T) {
+	cm := newConvergenceMonitor([]float64{0.5}, 0.95, 0.01, 0)
+	if cm.shouldStop(time.Hour) {
+		t.Error("Expected monitor without samples not to stop")
+	}
+
+	// Too few samples
+	cm.Log(10.0, newTestTxn(0.0, 0))
+	cm.endIteration()
+	if cm.shouldStop(0) {
+		t.Error("Expected monitor with a single sample not to stop")
+	}
+
+	// Time budget exceeded
+	cm = newConvergenceMonitor([]float64{0.5}, 0.95, 0.01, time.Minute)
+	if !cm.shouldStop(time.Hour) {
+		t.Error("Expected monitor to stop once the time budget is exceeded")
+	}
+}
+
+func TestConvergenceMonitorCensored(t *testing.T) {
+	cm := newConvergenceMonitor([]float64{0.5, 2.0}, 0.95, 0.01, 0)
+
+	// Spike 1 confirms nothing behind its backlog
+	for i := 0; i < MIN_ADAPTIVE_ITERATIONS-1; i++ {
+		cm.Log(10.0, newTestTxn(0.0, 0))
+		pool := newMempool()
+		pool.add(newTestTxn(0.0, 1))
+		cm.EndIteration(1000.0, pool)
+		cm.endIteration()
+	}
+	if mean, _, n := cm.estimate(1); n != MIN_ADAPTIVE_ITERATIONS-1 || mean != 1000.0 {
+		t.Error("Expected samples of the unconfirmed txns' age, got", n, "with mean", mean)
+	}
+
+	// Spike 0 has enough samples, but spike 1 does not
+	cm.Log(10.0, newTestTxn(0.0, 0))
+	cm.endIteration()
+	if cm.converged() {
+		t.Error("Expected every spike to need", MIN_ADAPTIVE_ITERATIONS, "samples")
+	}
+}
+
+func TestAdaptiveStopping(t *testing.T) {
+	sp := &SpikeProfile{
+		[]Spike{
+			Spike{0.0, 0.1},
+		},
+	}
+
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(MIN_ADAPTIVE_ITERATIONS)).
+		UseSpikeProfile(sp).
+		UseAdaptiveStopping(0.95, 1.0, 0)
+	sim.Run()
+
+	if sim.Iterations() != MIN_ADAPTIVE_ITERATIONS {
+		t.Error("Expected", MIN_ADAPTIVE_ITERATIONS, "iterations, got", sim.Iterations())
+	}
+}
@@ -7,11 +7,13 @@ import (
 
 /**
  * Stores the `cumulativePlot` for each spike in a `SpikeProfile` and the file
- * prefix for the output files.
+ * prefix for the output files.  If `kaplanMeier` is set, the output includes a
+ * Kaplan-Meier estimate that accounts for `txn`s that were never confirmed.
  */
 type CumulativeLogger struct {
-	plots      []*cumulativePlot
-	filePrefix string
+	plots       []*cumulativePlot
+	filePrefix  string
+	kaplanMeier bool
 }
 
 /**
@@ -25,6 +27,9 @@ func (cl CumulativeLogger) FilePrefix() string {
  * @return - The file extension for `CumulativeLogger` output
  */
 func (cl CumulativeLogger) FileExtension() string {
+	if cl.kaplanMeier {
+		return "km-dat"
+	}
 	return "cl-dat"
 }
 
@@ -36,18 +41,7 @@ func (cl CumulativeLogger) FileExtension() string {
  *
  */
 func (cl *CumulativeLogger) Log(blockTimestamp float64, t txn) {
-	// Caclulate the log of a txn's confirmation time
-	age := blockTimestamp - t.time
-	logAge := math.Log10(age)
-	logAgeBucket := float64(NUM_BUCKETS_PER_ORDER) * logAge
-
-	b := int64(math.Ceil(logAgeBucket))
-	// Offset for negtive log values
-	b += NEGATIVE_ORDERS * NUM_BUCKETS_PER_ORDER
-
-	if b < 0 {
-		b = 0
-	}
+	b := confirmationBucket(blockTimestamp - t.time)
 	if b >= int64(len(cl.plots[t.index].buckets)) {
 		// diff := b - int64(len(cl.plots[t.index].buckets) - 1)
 
@@ -59,6 +53,25 @@ func (cl *CumulativeLogger) Log(blockTimestamp float64, t txn) {
 	cl.plots[t.index].incrementBucket(b)
 }
 
+/**
+ * Records the `txn`s that remain unconfirmed at the end of an iteration as
+ * censored observations, their age at the end of the iteration is a lower
+ * bound on their confirmation time.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (cl *CumulativeLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	for _, t := range pool.txns {
+		b := confirmationBucket(endTimestamp - t.time)
+		if b >= NUM_BUCKETS {
+			b = NUM_BUCKETS - 1
+		}
+
+		cl.plots[t.index].censor(b)
+	}
+}
+
 /**
  * Accumulates the file contents for all `cumulativePlot`s.
  *
@@ -67,7 +80,14 @@ func (cl *CumulativeLogger) Log(blockTimestamp float64, t txn) {
 func (cl *CumulativeLogger) Outputs() (outputs []string) {
 	for i, plot := range cl.plots {
 		fmt.Println("[CumulativePlot]: generating cumulative plot data for spike", i)
-		outputs = append(outputs, plot.output())
+		fmt.Println(fmt.Sprintf("     %d confirmed, %d censored (%.2f%%)",
+			plot.txnCount, plot.censoredCount, 100*plot.censoredFraction()))
+
+		if cl.kaplanMeier {
+			outputs = append(outputs, plot.kaplanMeierOutput())
+		} else {
+			outputs = append(outputs, plot.output())
+		}
 	}
 	return
 }
@@ -85,13 +105,16 @@ func (cl *CumulativeLogger) Reset() {
  * Stores the buckets as an array of counters.  The number in each bucket
  * represents the number of txn's whose confirmation times fall within that bucket.
  * Also maintains a count of the total `txn`s recorded and the range of buckets
- * in use.
+ * in use.  Unconfirmed `txn`s are counted separately in `censored`, bucketed
+ * by their age at the end of the iteration.
  */
 type cumulativePlot struct {
 	buckets        []int64
 	smallestBucket int64
 	largestBucket  int64
 	txnCount       int64
+	censored       []int64
+	censoredCount  int64
 }
 
 /**
@@ -105,6 +128,8 @@ func newCumulativePlot() *cumulativePlot {
 		smallestBucket: NUM_BUCKETS,
 		largestBucket:  0,
 		txnCount:       0,
+		censored:       make([]int64, NUM_BUCKETS),
+		censoredCount:  0,
 	}
 }
 
@@ -123,12 +148,36 @@ func (cp *cumulativePlot) incrementBucket(i int64) {
 	}
 }
 
+/**
+ * Records an unconfirmed `txn` in censored bucket `i`.
+ */
+func (cp *cumulativePlot) censor(i int64) {
+	cp.censored[i]++
+	cp.censoredCount++
+}
+
+/**
+ * @return - The fraction of `txn`s that were never confirmed
+ */
+func (cp *cumulativePlot) censoredFraction() float64 {
+	total := cp.txnCount + cp.censoredCount
+	if total == 0 {
+		return 0.0
+	}
+	return float64(cp.censoredCount) / float64(total)
+}
+
 /**
  *  Returns a string representation of the plot to be written to a file.
  *
  * @return - The file contents for this spike's plot.
  */
 func (cp *cumulativePlot) output() (fileContents string) {
+	// No txns were confirmed, e.g. if they are stuck behind a spike's backlog
+	if cp.txnCount == 0 {
+		return
+	}
+
 	cumulativeTotal := float64(0.0)
 	txnCountFloat := float64(cp.txnCount)
 
@@ -144,3 +193,163 @@ func (cp *cumulativePlot) output() (fileContents string) {
 	}
 	return
 }
+
+/**
+ * Returns a string representation of the plot with an additional Kaplan-Meier
+ * estimate of the cumulative probability.  Unlike the empirical cumulative
+ * probability, which only considers confirmed `txn`s, the estimate treats
+ * unconfirmed `txn`s as censored at their age when the iteration ended.
+ *
+ * Each row has the format:
+ * `<bucket-number> | <txn-confirmation-time> | <probability> | <cumulative-probability> | <kaplan-meier-cumulative-probability>`
+ *
+ * @return - The file contents for this spike's plot.
+ */
+func (cp *cumulativePlot) kaplanMeierOutput() (fileContents string) {
+	if cp.txnCount == 0 {
+		return
+	}
+
+	cumulativeTotal := float64(0.0)
+	txnCountFloat := float64(cp.txnCount)
+
+	// Txns censored before the first confirmation are no longer at risk
+	atRisk := cp.txnCount + cp.censoredCount
+	for i := int64(0); i < cp.smallestBucket; i++ {
+		atRisk -= cp.censored[i]
+	}
+
+	survival := 1.0
+	for i := cp.smallestBucket; i <= cp.largestBucket; i++ {
+		count := cp.buckets[i]
+		cumulativeTotal += float64(count)
+
+		if atRisk > 0 {
+			survival *= 1.0 - float64(count)/float64(atRisk)
+		}
+		// Txns censored in this bucket are at risk until its end
+		atRisk -= count + cp.censored[i]
+
+		if count == 0 {
+			continue
+		}
+
+		fileContents += fmt.Sprintf("%d | %f | %f | %f | %f\n",
+			i,
+			bucketTime(i),
+			float64(count)/txnCountFloat,
+			cumulativeTotal/txnCountFloat,
+			1.0-survival)
+	}
+	return
+}
+
+/**
+ * Calculates the confirmation time below which the fraction `q` of the
+ * recorded `txn`s fall.
+ *
+ * @param q - The desired quantile in [0, 1]
+ *
+ * @return - The confirmation time (in seconds) of the bucket containing the
+ *           `q` quantile, or 0 if no `txn`s have been recorded.
+ */
+func (cp *cumulativePlot) quantile(q float64) float64 {
+	if cp.txnCount == 0 {
+		return 0.0
+	}
+
+	threshold := q * float64(cp.txnCount)
+	cumulativeTotal := float64(0.0)
+	for i := cp.smallestBucket; i <= cp.largestBucket; i++ {
+		cumulativeTotal += float64(cp.buckets[i])
+		if cumulativeTotal >= threshold {
+			return bucketTime(i)
+		}
+	}
+	return bucketTime(cp.largestBucket)
+}
+
+/**
+ * Calculates the quantile like `quantile`, counting each censored `txn` at
+ * its age when the iteration ended.
+ *
+ * @param q - The desired quantile in [0, 1]
+ *
+ * @return - The time (in seconds) of the bucket containing the `q` quantile,
+ *           or 0 if no `txn`s have been recorded.
+ */
+func (cp *cumulativePlot) censoredQuantile(q float64) float64 {
+	total := cp.txnCount + cp.censoredCount
+	if total == 0 {
+		return 0.0
+	}
+
+	threshold := q * float64(total)
+	cumulativeTotal := float64(0.0)
+	for i := int64(0); i < NUM_BUCKETS; i++ {
+		cumulativeTotal += float64(cp.buckets[i] + cp.censored[i])
+		if cumulativeTotal >= threshold {
+			return bucketTime(i)
+		}
+	}
+	return bucketTime(NUM_BUCKETS - 1)
+}
+
+/**
+ * Computes the cumulative probability of confirming within `t` seconds.
+ *
+ * @param t - The confirmation time in seconds
+ *
+ * @return - The fraction of recorded `txn`s in buckets up to the one
+ *           containing `t`, or 0 if no `txn`s have been recorded.
+ */
+func (cp *cumulativePlot) probability(t float64) float64 {
+	if cp.txnCount == 0 {
+		return 0.0
+	}
+
+	last := confirmationBucket(t)
+	if last > cp.largestBucket {
+		last = cp.largestBucket
+	}
+
+	cumulativeTotal := int64(0)
+	for i := cp.smallestBucket; i <= last; i++ {
+		cumulativeTotal += cp.buckets[i]
+	}
+	return float64(cumulativeTotal) / float64(cp.txnCount)
+}
+
+/**
+ * Converts a confirmation time into its bucket index.  Times below the
+ * smallest bucket are placed in bucket 0, there is no upper bound.
+ *
+ * @param age - The confirmation time in seconds
+ *
+ * @return - The bucket index for `age`
+ */
+func confirmationBucket(age float64) int64 {
+	// Caclulate the log of a txn's confirmation time
+	logAge := math.Log10(age)
+	logAgeBucket := float64(NUM_BUCKETS_PER_ORDER) * logAge
+
+	b := int64(math.Ceil(logAgeBucket))
+	// Offset for negtive log values
+	b += NEGATIVE_ORDERS * NUM_BUCKETS_PER_ORDER
+
+	if b < 0 {
+		b = 0
+	}
+	return b
+}
+
+/**
+ * Converts a bucket index into the confirmation time it represents.
+ *
+ * @param b - The bucket index
+ *
+ * @return - The confirmation time (in seconds) for bucket `b`
+ */
+func bucketTime(b int64) float64 {
+	return math.Pow(10.0, float64(b-(NEGATIVE_ORDERS*NUM_BUCKETS_PER_ORDER))/float64(NUM_BUCKETS_PER_ORDER))
+}
@@ -8,6 +8,7 @@ func TestFilePrefix(t *testing.T) {
 	cl := CumulativeLogger{
 		[]*cumulativePlot{},
 		expectedPrefix,
+		false,
 	}
 
 	if cl.FilePrefix() != expectedPrefix {
@@ -21,6 +22,7 @@ func TestFileExtension(t *testing.T) {
 	cl := CumulativeLogger{
 		[]*cumulativePlot{},
 		"",
+		false,
 	}
 
 	if cl.FileExtension() != expectedExtension {
@@ -36,25 +38,25 @@ var logTests = []struct {
 }{
 	{
 		0.0,
-		txn{0.0, 0.0},
+		newTestTxn(0.0, 0.0),
 		0,
 		false,
 	},
 	{
 		10.0,
-		txn{0.0, 0.0},
+		newTestTxn(0.0, 0.0),
 		2000,
 		false,
 	},
 	{
 		10000.0,
-		txn{0.0, 0.0},
+		newTestTxn(0.0, 0.0),
 		5000,
 		false,
 	},
 	{
 		100000000000000000000, // Some very high number
-		txn{0.0, 0.0},
+		newTestTxn(0.0, 0.0),
 		0, // Not used, test for panicking instead
 		true,
 	},
@@ -78,6 +80,7 @@ func TestLog(t *testing.T) {
 		cl := CumulativeLogger{
 			[]*cumulativePlot{newCumulativePlot()},
 			"",
+			false,
 		}
 		cl.Log(test.blockTimestamp, test.t)
 
@@ -100,9 +103,10 @@ func TestOutput(t *testing.T) {
 	cl := CumulativeLogger{
 		[]*cumulativePlot{newCumulativePlot()},
 		"",
+		false,
 	}
 	for i := float64(0); i < 5; i++ {
-		cl.Log(1000.0, txn{i, 0})
+		cl.Log(1000.0, newTestTxn(i, 0))
 	}
 
 	output := cl.Outputs()[0]
@@ -111,3 +115,38 @@ func TestOutput(t *testing.T) {
 		t.Error("Expected output '", expectedOutput, "', got '", output, "'")
 	}
 }
+
+func TestKaplanMeierOutput(t *testing.T) {
+	expectedOutput := "2000 | 10.000000 | 0.500000 | 0.500000 | 0.200000\n" +
+		"4000 | 1000.000000 | 0.500000 | 1.000000 | 0.466667\n"
+
+	cl := CumulativeLogger{
+		[]*cumulativePlot{newCumulativePlot()},
+		"",
+		true,
+	}
+	if cl.FileExtension() != "km-dat" {
+		t.Error("Expected file extension km-dat, got", cl.FileExtension())
+	}
+
+	// Two confirmed txns, one censored after 1 second, one after 100 seconds
+	// and two after 10000 seconds
+	cl.Log(10.0, newTestTxn(0.0, 0))
+	cl.Log(1000.0, newTestTxn(0.0, 0))
+
+	pool := newMempool()
+	pool.add(newTestTxn(9999.0, 0))
+	pool.add(newTestTxn(9900.0, 0))
+	pool.add(newTestTxn(0.0, 0))
+	pool.add(newTestTxn(0.0, 0))
+	cl.EndIteration(10000.0, pool)
+
+	if cl.plots[0].censoredCount != 4 {
+		t.Error("Expected 4 censored txns, got", cl.plots[0].censoredCount)
+	}
+
+	output := cl.Outputs()[0]
+	if output != expectedOutput {
+		t.Error("Expected output '", expectedOutput, "', got '", output, "'")
+	}
+}
@@ -0,0 +1,151 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Kinds of `Distribution`
+const (
+	DISTRIBUTION_CONSTANT    = "constant"
+	DISTRIBUTION_UNIFORM     = "uniform"
+	DISTRIBUTION_EXPONENTIAL = "exponential"
+	DISTRIBUTION_LOGNORMAL   = "lognormal"
+)
+
+/**
+ * `Distribution`
+ *
+ * A distribution of positive values, such as `txn` sizes or fee rates.  The
+ * parameters used depend on the `Kind`:
+ *   constant:    `Value`
+ *   uniform:     `Min`, `Max`
+ *   exponential: `Mean`
+ *   lognormal:   `Median`, `Sigma` (of the logarithm)
+ */
+type Distribution struct {
+	Kind   string  `json:"kind"`
+	Value  float64 `json:"value,omitempty"`
+	Min    float64 `json:"min,omitempty"`
+	Max    float64 `json:"max,omitempty"`
+	Mean   float64 `json:"mean,omitempty"`
+	Median float64 `json:"median,omitempty"`
+	Sigma  float64 `json:"sigma,omitempty"`
+}
+
+/**
+ * @param value - The only value of the distribution
+ *
+ * @return - A constant `Distribution`
+ */
+func ConstantDistribution(value float64) Distribution {
+	return Distribution{Kind: DISTRIBUTION_CONSTANT, Value: value}
+}
+
+/**
+ * Draws a value from the distribution.  Constant distributions do not consume
+ * random numbers, so adding them leaves the other draws of `r` unchanged.
+ *
+ * @param r - The source of randomness
+ *
+ * @return - The sampled value
+ */
+func (d Distribution) Sample(r *rand.Rand) float64 {
+	switch d.Kind {
+	case DISTRIBUTION_UNIFORM:
+		return d.Min + (d.Max-d.Min)*r.Float64()
+	case DISTRIBUTION_EXPONENTIAL:
+		return drawFromPoissonWith(r.Float64(), 1.0/d.Mean)
+	case DISTRIBUTION_LOGNORMAL:
+		return d.Median * math.Exp(d.Sigma*r.NormFloat64())
+	}
+	return d.Value
+}
+
+/**
+ * @return - The mean of the distribution
+ */
+func (d Distribution) MeanValue() float64 {
+	switch d.Kind {
+	case DISTRIBUTION_UNIFORM:
+		return (d.Min + d.Max) / 2.0
+	case DISTRIBUTION_EXPONENTIAL:
+		return d.Mean
+	case DISTRIBUTION_LOGNORMAL:
+		return d.Median * math.Exp(d.Sigma*d.Sigma/2.0)
+	}
+	return d.Value
+}
+
+/**
+ * @return - The median of the distribution
+ */
+func (d Distribution) MedianValue() float64 {
+	switch d.Kind {
+	case DISTRIBUTION_UNIFORM:
+		return (d.Min + d.Max) / 2.0
+	case DISTRIBUTION_EXPONENTIAL:
+		return d.Mean * math.Ln2
+	case DISTRIBUTION_LOGNORMAL:
+		return d.Median
+	}
+	return d.Value
+}
+
+/**
+ * @return - The largest value of the distribution, infinite if it is
+ *           unbounded
+ */
+func (d Distribution) MaxValue() float64 {
+	switch d.Kind {
+	case DISTRIBUTION_UNIFORM:
+		return d.Max
+	case DISTRIBUTION_EXPONENTIAL, DISTRIBUTION_LOGNORMAL:
+		return math.Inf(1)
+	}
+	return d.Value
+}
+
+/**
+ * Verifies that the distribution has a known `Kind` and only produces
+ * non-negative values.
+ *
+ * @return - nil if the `Distribution` is valid, otherwise the first problem
+ */
+func (d Distribution) Validate() error {
+	switch d.Kind {
+	case DISTRIBUTION_CONSTANT:
+		if d.Value < 0.0 {
+			return fmt.Errorf("constant distribution value %f is negative", d.Value)
+		}
+	case DISTRIBUTION_UNIFORM:
+		if d.Min < 0.0 || d.Max < d.Min {
+			return fmt.Errorf("uniform distribution range [%f, %f] is invalid", d.Min, d.Max)
+		}
+	case DISTRIBUTION_EXPONENTIAL:
+		if d.Mean <= 0.0 {
+			return fmt.Errorf("exponential distribution mean %f is not positive", d.Mean)
+		}
+	case DISTRIBUTION_LOGNORMAL:
+		if d.Median <= 0.0 || d.Sigma < 0.0 {
+			return fmt.Errorf("lognormal distribution median %f or sigma %f is invalid", d.Median, d.Sigma)
+		}
+	default:
+		return fmt.Errorf("unknown distribution kind %q", d.Kind)
+	}
+	return nil
+}
+
+/**
+ * @return - Whether every sample is positive
+ */
+func (d Distribution) positive() bool {
+	switch d.Kind {
+	case DISTRIBUTION_CONSTANT:
+		return d.Value > 0.0
+	case DISTRIBUTION_UNIFORM:
+		return d.Min > 0.0
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+ * `FeeEstimateLogger`
+ *
+ * Evaluates the `FeeEstimator` from the point of view of its users, the
+ * `txn`s of `TxnClass`es with a `FeeTarget`.  A `txn` that paid the estimated
+ * fee rate hits its target if it is confirmed within `FeeTarget` blocks of
+ * its arrival, and misses it if it is confirmed later or is still unconfirmed
+ * `FeeTarget` blocks after its arrival.  The overpayment of a confirmed `txn`
+ * is the difference between its fee rate and the lowest fee rate included
+ * in its block, or its whole fee rate if the block was not full.  `txn`s that
+ * arrived while the estimator had no estimate pay their class's `FeeRate` and
+ * are only counted as fallbacks.
+ *
+ * Each output file, one per spike, has a single row with the format:
+ * `<target> | <estimated-txns> | <fallback-txns> | <hit-rate> | <mean-overpayment> | <mean-relative-overpayment>`
+ * where the overpayment is in satoshis per byte.
+ */
+type FeeEstimateLogger struct {
+	targets      []int
+	spikeTargets []int
+	stats        []*feeEstimateStats
+	timestamps   []float64
+	pending      []txn
+	filePrefix   string
+}
+
+/**
+ * Accumulates the outcomes of the estimated `txn`s of a single spike.
+ */
+type feeEstimateStats struct {
+	estimated       int64
+	fallbacks       int64
+	hits            int64
+	misses          int64
+	confirmed       int64
+	overpayment     float64
+	relativeOverpay float64
+}
+
+/**
+ * Initializes a new `FeeEstimateLogger`
+ *
+ * @param prefix - The file prefix for writing the output files
+ * @param classes - The `TxnClass`es of the simulation
+ *
+ * @return - An empty `FeeEstimateLogger`
+ */
+func newFeeEstimateLogger(prefix string, classes []TxnClass) *FeeEstimateLogger {
+	fel := &FeeEstimateLogger{filePrefix: prefix}
+	for _, class := range classes {
+		fel.targets = append(fel.targets, class.FeeTarget)
+	}
+	for _, spike := range classSpikes(classes) {
+		fel.spikeTargets = append(fel.spikeTargets, classes[spike.class].FeeTarget)
+		fel.stats = append(fel.stats, &feeEstimateStats{})
+	}
+	return fel
+}
+
+/**
+ * @return - The specified prefix for the output file.
+ */
+func (fel FeeEstimateLogger) FilePrefix() string {
+	return fel.filePrefix
+}
+
+/**
+ * @return - The file extension for `FeeEstimateLogger` output
+ */
+func (fel FeeEstimateLogger) FileExtension() string {
+	return "fe-dat"
+}
+
+/**
+ * Holds a newly included `txn` until its block is mined.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (fel *FeeEstimateLogger) Log(blockTimestamp float64, t txn) {
+	if fel.targets[t.class] > 0 {
+		fel.pending = append(fel.pending, t)
+	}
+}
+
+/**
+ * Evaluates the `txn`s included by the block against their targets.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (fel *FeeEstimateLogger) LogBlock(b block, pool *mempool) {
+	clearing := clearingFeeRate(b)
+	for _, t := range fel.pending {
+		stats := fel.stats[t.index]
+		if t.target == 0 {
+			stats.fallbacks++
+			continue
+		}
+
+		stats.estimated++
+		blocks := len(fel.timestamps) - blocksBefore(fel.timestamps, t.time) + 1
+		if blocks <= t.target {
+			stats.hits++
+		} else {
+			stats.misses++
+		}
+
+		stats.confirmed++
+		overpayment := t.feeRate - clearing
+		stats.overpayment += overpayment
+		if t.feeRate > 0.0 {
+			stats.relativeOverpay += overpayment / t.feeRate
+		}
+	}
+	fel.pending = fel.pending[:0]
+	fel.timestamps = append(fel.timestamps, b.timestamp)
+}
+
+/**
+ * Counts the unconfirmed `txn`s that already missed their target.  Younger
+ * `txn`s could still have hit it, so they are not counted.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (fel *FeeEstimateLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	for _, t := range pool.txns {
+		if fel.targets[t.class] == 0 {
+			continue
+		}
+
+		stats := fel.stats[t.index]
+		if t.target == 0 {
+			stats.fallbacks++
+			continue
+		}
+		waited := len(fel.timestamps) - blocksBefore(fel.timestamps, t.time)
+		if waited >= t.target {
+			stats.estimated++
+			stats.misses++
+		}
+	}
+	fel.timestamps = fel.timestamps[:0]
+}
+
+/**
+ * Summarizes the hit rate and overpayment of each spike.
+ *
+ * @return - The file contents for each spike
+ */
+func (fel *FeeEstimateLogger) Outputs() (outputs []string) {
+	for i, stats := range fel.stats {
+		target := fel.spikeTargets[i]
+		hitRate := float64(stats.hits) / float64(stats.hits+stats.misses)
+		overpayment := stats.overpayment / float64(stats.confirmed)
+		relative := stats.relativeOverpay / float64(stats.confirmed)
+		if stats.hits+stats.misses == 0 {
+			hitRate = math.NaN()
+		}
+		if stats.confirmed == 0 {
+			overpayment, relative = math.NaN(), math.NaN()
+		}
+
+		if target > 0 {
+			fmt.Println("[FeeEstimateLogger]: fee estimates for spike", i)
+			fmt.Println(fmt.Sprintf("     target %d blocks: %d estimated, %d fallbacks, hit rate %.4f, mean overpayment %.2f sat/byte (%.1f%%)",
+				target, stats.estimated, stats.fallbacks, hitRate, overpayment, 100*relative))
+		}
+		outputs = append(outputs, fmt.Sprintf("%d | %d | %d | %f | %f | %f\n",
+			target, stats.estimated, stats.fallbacks, hitRate, overpayment, relative))
+	}
+	return
+}
+
+/**
+ * Clears the logging state.
+ */
+func (fel *FeeEstimateLogger) Reset() {
+	for i := range fel.stats {
+		fel.stats[i] = &feeEstimateStats{}
+	}
+	fel.timestamps = []float64{}
+	fel.pending = []txn{}
+}
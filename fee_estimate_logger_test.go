@@ -0,0 +1,94 @@
+package bitcoin_load_spike
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFeeEstimateLogger(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	classes := []TxnClass{
+		TxnClass{Name: "other", SpikeProfile: sp, Size: ConstantDistribution(100.0), FeeRate: ConstantDistribution(1.0)},
+		TxnClass{Name: "wallet", SpikeProfile: sp, Size: ConstantDistribution(100.0), FeeRate: ConstantDistribution(1.0), FeeTarget: 2},
+	}
+	fel := newFeeEstimateLogger("", classes)
+
+	estimated := func(time, feeRate float64) txn {
+		return txn{time: time, index: 1, class: 1, size: 100.0, feeRate: feeRate, target: 2}
+	}
+	fallback := txn{time: 10.0, index: 1, class: 1, size: 100.0, feeRate: 5.0}
+
+	// Full block with a clearing fee rate of 4, confirming a txn in 1 block
+	fel.Log(600.0, estimated(10.0, 10.0))
+	fel.Log(600.0, fallback)
+	fel.Log(600.0, txn{time: 20.0, index: 0, class: 0, size: 100.0, feeRate: 4.0})
+	fel.LogBlock(block{height: 0, timestamp: 600.0, maxSize: 300.0, size: 300.0,
+		txns: []txn{estimated(10.0, 10.0), fallback, txn{feeRate: 4.0}}}, newMempool())
+
+	// Block with room left, confirming a txn in 3 blocks
+	fel.LogBlock(block{height: 1, timestamp: 1200.0, maxSize: 1000.0}, newMempool())
+	fel.Log(1800.0, estimated(20.0, 6.0))
+	fel.LogBlock(block{height: 2, timestamp: 1800.0, maxSize: 1000.0, size: 100.0}, newMempool())
+
+	// One txn already missed its target, the other may still hit it
+	pool := newMempool()
+	pool.add(estimated(700.0, 2.0))
+	pool.add(estimated(1500.0, 2.0))
+	fel.EndIteration(1800.0, pool)
+
+	stats := fel.stats[1]
+	if stats.estimated != 3 || stats.fallbacks != 1 || stats.hits != 1 || stats.misses != 2 {
+		t.Error("Expected 3 estimated, 1 fallback, 1 hit and 2 misses, got", *stats)
+	}
+	if stats.overpayment != 6.0+6.0 || math.Abs(stats.relativeOverpay-1.6) > 1e-9 {
+		t.Error("Expected overpayments of 6 and 6 sat/byte, got", stats.overpayment, stats.relativeOverpay)
+	}
+
+	outputs := fel.Outputs()
+	if len(outputs) != 2 || !strings.HasPrefix(outputs[0], "0 | 0 | 0 | NaN") {
+		t.Error("Expected an empty row for the class without a target, got", outputs)
+	}
+	if outputs[1] != "2 | 3 | 1 | 0.333333 | 6.000000 | 0.800000\n" {
+		t.Error("Expected the wallet's row, got", outputs[1])
+	}
+}
+
+func TestSimulateFeeEstimates(t *testing.T) {
+	// Congested blocks with fee rates spread over two orders of magnitude
+	classes := []TxnClass{
+		TxnClass{
+			Name:         "background",
+			SpikeProfile: SpikeProfile{[]Spike{Spike{0.0, 1.2}}},
+			Size:         ConstantDistribution(400.0),
+			FeeRate:      Distribution{Kind: DISTRIBUTION_LOGNORMAL, Median: 10.0, Sigma: 1.0},
+		},
+		TxnClass{
+			Name:         "wallet",
+			SpikeProfile: SpikeProfile{[]Spike{Spike{0.0, 0.05}}},
+			Size:         ConstantDistribution(250.0),
+			FeeRate:      ConstantDistribution(20.0),
+			FeeTarget:    2,
+		},
+	}
+
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(150), int64(2)).
+		UseTxnClasses(classes).
+		UseWarmUp(30, 0.0).
+		UseSeed(3)
+	fel := newFeeEstimateLogger("", classes)
+	sim.loggers = append(sim.loggers, fel)
+	sim.simulate(false)
+
+	stats := fel.stats[1]
+	if stats.estimated == 0 {
+		t.Fatal("Expected txns paying estimated fee rates")
+	}
+	hitRate := float64(stats.hits) / float64(stats.hits+stats.misses)
+	if hitRate < 0.7 || hitRate > 0.99 {
+		t.Error("Expected a hit rate near the success threshold, got", hitRate)
+	}
+	if stats.overpayment <= 0.0 {
+		t.Error("Expected some overpayment, got", stats.overpayment)
+	}
+}
@@ -0,0 +1,243 @@
+package bitcoin_load_spike
+
+import "sort"
+
+/**
+ * `FeeEstimator`
+ *
+ * Suggests fee rates from the blocks and `mempool` of the running
+ * simulation.  `TxnClass`es with a `FeeTarget` ask the estimator for the fee
+ * rate that confirms within that many blocks when each `txn` arrives, and
+ * fall back to their `FeeRate` while the estimator has no estimate.
+ */
+type FeeEstimator interface {
+	// Observes a mined block and the `mempool` after its `txn`s were removed
+	ObserveBlock(b block, pool *mempool)
+	// Returns the fee rate in satoshis per byte to confirm within `target`
+	// blocks, or false if there is not enough data
+	EstimateFee(target int) (float64, bool)
+	// Forgets the observed history before a new iteration
+	Reset()
+}
+
+/**
+ * `BucketFeeEstimator`
+ *
+ * A `FeeEstimator` modeled on Bitcoin Core's `estimatesmartfee`.  Confirmed
+ * `txn`s are grouped into exponentially spaced fee rate buckets, and for
+ * each target the estimator tracks how many `txn`s of each bucket confirmed
+ * within that many blocks of their arrival.  `txn`s still in the `mempool`
+ * after `target` blocks count as failures.  Every count decays by `Decay`
+ * per block.
+ *
+ * An estimate scans the buckets from the highest fee rate down, merging
+ * buckets until they hold `SufficientTxns / (1 - Decay)` `txn`s, and stops at
+ * the first group that confirms less than `SuccessThreshold` of its `txn`s in
+ * time.  The estimate is the mean fee rate of the last group that succeeded.
+ */
+type BucketFeeEstimator struct {
+	MinFeeRate       float64
+	MaxFeeRate       float64
+	Spacing          float64
+	Decay            float64
+	MaxTarget        int
+	SuccessThreshold float64
+	SufficientTxns   float64
+
+	boundaries []float64
+	timestamps []float64
+	txnCounts  []float64
+	feeSums    []float64
+	confirmed  [][]float64
+	stuck      [][]float64
+}
+
+/**
+ * Initializes a new `BucketFeeEstimator` with the parameters of Bitcoin
+ * Core's medium horizon and economical estimates.
+ *
+ * @return - An empty `BucketFeeEstimator`
+ */
+func NewBucketFeeEstimator() *BucketFeeEstimator {
+	fe := &BucketFeeEstimator{
+		MinFeeRate:       1.0,
+		MaxFeeRate:       1e4,
+		Spacing:          1.05,
+		Decay:            0.998,
+		MaxTarget:        48,
+		SuccessThreshold: 0.85,
+		SufficientTxns:   0.1,
+	}
+	fe.Reset()
+	return fe
+}
+
+/**
+ * Clears the observed blocks and bucket statistics.  The buckets are
+ * rebuilt from the current parameters.
+ */
+func (fe *BucketFeeEstimator) Reset() {
+	fe.boundaries = []float64{}
+	for rate := fe.MinFeeRate; rate < fe.MaxFeeRate; rate *= fe.Spacing {
+		fe.boundaries = append(fe.boundaries, rate)
+	}
+
+	numBuckets := len(fe.boundaries) + 1
+	fe.timestamps = []float64{}
+	fe.txnCounts = make([]float64, numBuckets)
+	fe.feeSums = make([]float64, numBuckets)
+	fe.confirmed = make([][]float64, fe.MaxTarget)
+	fe.stuck = make([][]float64, fe.MaxTarget)
+	for i := range fe.confirmed {
+		fe.confirmed[i] = make([]float64, numBuckets)
+		fe.stuck[i] = make([]float64, numBuckets)
+	}
+}
+
+/**
+ * Decays the statistics and records the confirmation of each `txn` of the
+ * block, then counts the `txn`s in `pool` that have waited for each target.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (fe *BucketFeeEstimator) ObserveBlock(b block, pool *mempool) {
+	for i := range fe.txnCounts {
+		fe.txnCounts[i] *= fe.Decay
+		fe.feeSums[i] *= fe.Decay
+		for target := range fe.confirmed {
+			fe.confirmed[target][i] *= fe.Decay
+		}
+	}
+
+	// The block confirms txns that arrived before it, so it is not yet one of
+	// the timestamps
+	for _, t := range b.txns {
+		bucket := fe.bucket(t.feeRate)
+		fe.txnCounts[bucket]++
+		fe.feeSums[bucket] += t.feeRate
+
+		blocks := len(fe.timestamps) - blocksBefore(fe.timestamps, t.time) + 1
+		for target := blocks; target <= fe.MaxTarget; target++ {
+			fe.confirmed[target-1][bucket]++
+		}
+	}
+	fe.timestamps = append(fe.timestamps, b.timestamp)
+
+	// Pending txns that waited `target` blocks can no longer confirm in time
+	for target := range fe.stuck {
+		for i := range fe.stuck[target] {
+			fe.stuck[target][i] = 0.0
+		}
+	}
+	for _, t := range pool.txns {
+		waited := len(fe.timestamps) - blocksBefore(fe.timestamps, t.time)
+		if waited > fe.MaxTarget {
+			waited = fe.MaxTarget
+		}
+		if waited > 0 {
+			fe.stuck[waited-1][fe.bucket(t.feeRate)]++
+		}
+	}
+	// A txn that waited `waited` blocks is stuck for every smaller target
+	for target := fe.MaxTarget - 1; target >= 1; target-- {
+		for i := range fe.stuck[target-1] {
+			fe.stuck[target-1][i] += fe.stuck[target][i]
+		}
+	}
+}
+
+/**
+ * Estimates the lowest fee rate that confirmed within `target` blocks often
+ * enough.  Targets above `MaxTarget` use `MaxTarget`.
+ *
+ * @param target - The number of blocks in which to confirm
+ *
+ * @return - The fee rate in satoshis per byte, or false if no group of
+ *           buckets has enough `txn`s and succeeded
+ */
+func (fe *BucketFeeEstimator) EstimateFee(target int) (float64, bool) {
+	if target < 1 {
+		target = 1
+	}
+	if target > fe.MaxTarget {
+		target = fe.MaxTarget
+	}
+	confirmed, stuck := fe.confirmed[target-1], fe.stuck[target-1]
+	sufficient := fe.SufficientTxns / (1.0 - fe.Decay)
+
+	found := false
+	bestLow, bestHigh := 0, 0
+	numConfirmed, numTxns, numStuck := 0.0, 0.0, 0.0
+	high := len(fe.txnCounts) - 1
+	for i := len(fe.txnCounts) - 1; i >= 0; i-- {
+		numConfirmed += confirmed[i]
+		numTxns += fe.txnCounts[i]
+		numStuck += stuck[i]
+		if numTxns < sufficient {
+			continue
+		}
+
+		if numConfirmed/(numTxns+numStuck) < fe.SuccessThreshold {
+			break
+		}
+		found = true
+		bestLow, bestHigh = i, high
+		numConfirmed, numTxns, numStuck = 0.0, 0.0, 0.0
+		high = i - 1
+	}
+	if !found {
+		return 0.0, false
+	}
+
+	count, sum := 0.0, 0.0
+	for i := bestLow; i <= bestHigh; i++ {
+		count += fe.txnCounts[i]
+		sum += fe.feeSums[i]
+	}
+	return sum / count, true
+}
+
+/**
+ * @param feeRate - A fee rate in satoshis per byte
+ *
+ * @return - The index of the bucket containing `feeRate`, bucket 0 holds the
+ *           fee rates below `MinFeeRate`
+ */
+func (fe *BucketFeeEstimator) bucket(feeRate float64) int {
+	return sort.Search(len(fe.boundaries), func(i int) bool {
+		return fe.boundaries[i] > feeRate
+	})
+}
+
+/**
+ * Counts the blocks mined at or before `timestamp`, i.e. the height of the
+ * first block that could include a `txn` arriving at `timestamp`.
+ *
+ * @param timestamps - The timestamps of the mined blocks, in order
+ * @param timestamp - The arrival time of a `txn`
+ *
+ * @return - The number of blocks mined at or before `timestamp`
+ */
+func blocksBefore(timestamps []float64, timestamp float64) int {
+	return sort.Search(len(timestamps), func(i int) bool {
+		return timestamps[i] > timestamp
+	})
+}
+
+/**
+ * Returns the lowest fee rate a `txn` needed to be included in the block,
+ * which is 0 unless the block is full.
+ *
+ * @param b - The mined block
+ *
+ * @return - The lowest fee rate in the full block in satoshis per byte
+ */
+func clearingFeeRate(b block) float64 {
+	if !b.full() {
+		return 0.0
+	}
+
+	rate, _ := b.minFeeRate()
+	return rate
+}
@@ -0,0 +1,72 @@
+package bitcoin_load_spike
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBucketFeeEstimator(t *testing.T) {
+	fe := NewBucketFeeEstimator()
+	if _, ok := fe.EstimateFee(1); ok {
+		t.Error("Expected no estimate without history")
+	}
+
+	// Txns paying 50 sat/byte confirm in the next block, txns paying 2
+	// sat/byte wait 3 blocks
+	for height := int64(0); height < 200; height++ {
+		timestamp := float64(600 * (height + 1))
+		txns := []txn{}
+		for i := 0; i < 10; i++ {
+			fast := newTestTxn(timestamp-300.0, 0)
+			fast.feeRate = 50.0
+			txns = append(txns, fast)
+			if height >= 3 {
+				slow := newTestTxn(timestamp-3*600.0+300.0, 0)
+				slow.feeRate = 2.0
+				txns = append(txns, slow)
+			}
+		}
+		fe.ObserveBlock(block{height: height, timestamp: timestamp, txns: txns}, newMempool())
+	}
+
+	estimate, ok := fe.EstimateFee(1)
+	if !ok || math.Abs(estimate-50.0) > 1e-9 {
+		t.Error("Expected an estimate of 50 sat/byte for 1 block, got", estimate, ok)
+	}
+	estimate, ok = fe.EstimateFee(3)
+	if !ok || math.Abs(estimate-2.0) > 1e-9 {
+		t.Error("Expected an estimate of 2 sat/byte for 3 blocks, got", estimate, ok)
+	}
+
+	// Txns stuck in the mempool count as failures
+	pool := newMempool()
+	for i := 0; i < 1000; i++ {
+		stuck := newTestTxn(0.0, 0)
+		stuck.feeRate = 2.0
+		pool.add(stuck)
+	}
+	fe.ObserveBlock(block{height: 200, timestamp: 600 * 201}, pool)
+	estimate, ok = fe.EstimateFee(3)
+	if !ok || math.Abs(estimate-50.0) > 1e-9 {
+		t.Error("Expected stuck txns to raise the estimate, got", estimate, ok)
+	}
+
+	fe.Reset()
+	if _, ok := fe.EstimateFee(1); ok {
+		t.Error("Expected reset to clear the history")
+	}
+}
+
+func TestClearingFeeRate(t *testing.T) {
+	txns := []txn{newTestTxn(0.0, 0), newTestTxn(1.0, 0)}
+	txns[0].feeRate = 3.0
+
+	full := block{maxSize: 2 * BITCOIN_TRANSACTION_SIZE, size: 2 * BITCOIN_TRANSACTION_SIZE, txns: txns}
+	if rate := clearingFeeRate(full); rate != DEFAULT_FEE_RATE {
+		t.Error("Expected the lowest fee rate of a full block, got", rate)
+	}
+	full.maxSize = 3 * BITCOIN_TRANSACTION_SIZE
+	if rate := clearingFeeRate(full); rate != 0.0 {
+		t.Error("Expected 0 for a block with room left, got", rate)
+	}
+}
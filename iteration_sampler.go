@@ -0,0 +1,74 @@
+package bitcoin_load_spike
+
+import "math"
+
+/**
+ * `IterationQuantiles`
+ *
+ * Confirmation time quantiles of a single spike in a single iteration, NaN
+ * if the spike confirmed no `txn`s in that iteration.
+ */
+type IterationQuantiles struct {
+	Median float64
+	P95    float64
+	P99    float64
+}
+
+/**
+ * `iterationSampler`
+ *
+ * Records the confirmation time quantiles of every spike separately for each
+ * iteration, so that simulations sharing a seed can be compared iteration by
+ * iteration.
+ */
+type iterationSampler struct {
+	current []*cumulativePlot
+	samples [][]IterationQuantiles
+}
+
+/**
+ * Initializes a new `iterationSampler` for a `SpikeProfile` with `numSpikes`.
+ *
+ * @param numSpikes - Number of spikes to sample
+ *
+ * @return - The new `iterationSampler`
+ */
+func newIterationSampler(numSpikes int) *iterationSampler {
+	is := &iterationSampler{
+		current: make([]*cumulativePlot, numSpikes),
+		samples: make([][]IterationQuantiles, numSpikes),
+	}
+	for i := range is.current {
+		is.current[i] = newCumulativePlot()
+	}
+	return is
+}
+
+/**
+ * Records the confirmation time of `t` for the current iteration.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (is *iterationSampler) Log(blockTimestamp float64, t txn) {
+	b := confirmationBucket(blockTimestamp - t.time)
+	if b >= NUM_BUCKETS {
+		b = NUM_BUCKETS - 1
+	}
+
+	is.current[t.index].incrementBucket(b)
+}
+
+/**
+ * Closes the current iteration, storing the quantiles of each spike.
+ */
+func (is *iterationSampler) endIteration() {
+	for i, plot := range is.current {
+		sample := IterationQuantiles{math.NaN(), math.NaN(), math.NaN()}
+		if plot.txnCount > 0 {
+			sample = IterationQuantiles{plot.quantile(0.5), plot.quantile(0.95), plot.quantile(0.99)}
+		}
+		is.samples[i] = append(is.samples[i], sample)
+		is.current[i] = newCumulativePlot()
+	}
+}
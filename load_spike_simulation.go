@@ -1,8 +1,10 @@
 package bitcoin_load_spike
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"time"
 )
@@ -10,11 +12,23 @@ import (
 /**
  * `txn`
  *
- * Records time and spike index of the transaction's creation.
+ * Records time and spike index of the transaction's creation, along with its
+ * `TxnClass`, size in bytes and fee rate in satoshis per byte.  The spike
+ * index numbers the spikes of every class consecutively, see `classSpike`.
+ * A batch also records the arrival times of its `payments`, a `txn` of a
+ * class without `Batching` is a single payment arriving at `time`.  `target`
+ * is the `FeeTarget` the fee rate was estimated for, 0 if it was not.
+ * `maxFeeRate` is the highest fee rate its user would bid, see `Bidding`.
  */
 type txn struct {
-	time  float64
-	index int
+	time       float64
+	index      int
+	class      int
+	size       float64
+	feeRate    float64
+	payments   []float64
+	target     int
+	maxFeeRate float64
 }
 
 /**
@@ -24,16 +38,46 @@ type txn struct {
  * execution.
  */
 type LoadSpikeSimulation struct {
-	numBlocks     int64
-	numIterations int64
-	blockSize     float64
-	spikeProfile  *SpikeProfile
-	loggers       []Logger
+	numBlocks      int64
+	numIterations  int64
+	blockSize      float64
+	classes        []TxnClass
+	spikes         []classSpike
+	miners         []Miner
+	spv            *SPVMining
+	propagation    *Propagation
+	sizePolicy     BlockSizePolicy
+	penalty        *Penalty
+	loggers        []Logger
+	monitor        *convergenceMonitor
+	iterations     int64
+	warmUpBlocks   int64
+	warmUpSeconds  float64
+	steadyState    bool
+	detectedBlocks int64
+	seed           int64
+	seeded         bool
+	txnRand        *rand.Rand
+	blockRand      *rand.Rand
+	summary        *CumulativeLogger
+	sampler        *iterationSampler
+	results        *Results
+	ctx            context.Context
+	progress       func(iterations int64)
+	estimator      FeeEstimator
+	abstained      []int64
+	minerBlocks    []int64
+	minerSizes     []float64
+	spvBlocks      int64
+	orphaned       int64
+	reorgs         []int64
+	penalized      int64
+	penalties      float64
 }
 
 /**
  * Initializes a new `LoadSpikeSimulation` with the simulation parameters.  By
- * default, the `spikeProfile` is not specified.
+ * default, no `SpikeProfile` or `TxnClass`es are specified.
  *
  * @param bs - the maximum block size in bytes
  * @param nb - number of blocks to mine in a single iteration
@@ -42,155 +86,940 @@ type LoadSpikeSimulation struct {
  * @return - the new `LoadSpikeSimulation`
  */
 func NewLoadSpikeSimulation(bs float64, nb, ni int64) *LoadSpikeSimulation {
-	return &LoadSpikeSimulation{
+	lss := &LoadSpikeSimulation{
 		numBlocks:     nb,
 		numIterations: ni,
 		blockSize:     bs,
-		spikeProfile:  nil,
+		miners:        []Miner{defaultMiner()},
 		loggers:       []Logger{},
 	}
+	lss.reseed(time.Now().UTC().UnixNano())
+
+	return lss
 }
 
 /**
  * Runs the simulation, printing the parameters and progress bar.  `Logger`s
  * accumulate data about the simulation and are printed after the simulation
- * terminates.  `Run` will panic if no `SpikeProfile` has been set.
+ * terminates.  If adaptive stopping is enabled, the simulation may stop before
+ * `numIterations` once the results converge.  `Run` will panic if no
+ * `SpikeProfile` or `TxnClass`es have been set.
  */
 func (lss *LoadSpikeSimulation) Run() {
-	if lss.spikeProfile == nil {
+	lss.simulate(true)
+	lss.outputResults()
+
+	// Reset loggers in case the simulation is reused
+	lss.resetLoggers()
+}
+
+/**
+ * Runs the simulation without printing or writing any files, e.g. to run
+ * several simulations in parallel.  `Logger` outputs are discarded.
+ *
+ * @return - The summary of the simulation
+ */
+func (lss *LoadSpikeSimulation) Simulate() *Results {
+	lss.simulate(false)
+	lss.resetLoggers()
+
+	return lss.results
+}
+
+/**
+ * Performs the iterations of the simulation and summarizes the results.
+ *
+ * @param verbose - Whether to print the parameters and progress bar
+ */
+func (lss *LoadSpikeSimulation) simulate(verbose bool) {
+	if len(lss.classes) == 0 {
 		panic("Cannot run LoadSpikeSimulation without a SpikeProfile")
 	}
 
 	// Make sure to seed our randomness
-	rand.Seed(time.Now().UTC().UnixNano())
+	if !lss.seeded {
+		lss.seed = time.Now().UTC().UnixNano()
+	}
+	if lss.estimator == nil && useFeeEstimates(lss.classes) {
+		lss.estimator = NewBucketFeeEstimator()
+	}
+	lss.summary = lss.newCumulativeLogger("", false)
+	lss.sampler = newIterationSampler(len(lss.spikes))
 
 	// Print simulation parameters
-	fmt.Println("[LoadSpikeSimulation]")
-	fmt.Println("     iterations:", lss.numIterations)
-	fmt.Println("     blocks/iteration:", lss.numBlocks)
-	fmt.Println("     block size:", lss.blockSize)
-	fmt.Println("[SpikeProfile]")
-	lss.spikeProfile.PrintProfile()
+	if verbose {
+		fmt.Println("[LoadSpikeSimulation]")
+		fmt.Println("     iterations:", lss.numIterations)
+		fmt.Println("     blocks/iteration:", lss.numBlocks)
+		fmt.Println("     block size:", lss.blockSize)
+		fmt.Println("     seed:", lss.seed)
+		lss.printClasses()
+		lss.printMiners()
+	}
+
+	// Determine the truncation point with a pilot iteration
+	lss.abstained = nil
+	lss.minerBlocks, lss.reorgs = nil, nil
+	if lss.steadyState {
+		lss.detectSteadyState()
+	}
+	if verbose && (lss.warmUpBlocks > 0 || lss.warmUpSeconds > 0 || lss.steadyState) {
+		fmt.Println("[WarmUp]")
+		fmt.Println("     blocks:", lss.effectiveWarmUpBlocks())
+		fmt.Println("     seconds:", lss.warmUpSeconds)
+	}
+
+	// Calculate divisor for progress bar
+	divisor := lss.numIterations / 100
+	if divisor == 0 {
+		divisor = 1
+	}
+
+	// Run simulation
+	start := time.Now()
+	lss.iterations = 0
+	lss.abstained = make([]int64, len(lss.spikes))
+	lss.minerBlocks = make([]int64, len(lss.miners))
+	lss.minerSizes = make([]float64, len(lss.miners))
+	lss.spvBlocks = 0
+	lss.orphaned = 0
+	lss.reorgs = []int64{}
+	lss.penalized, lss.penalties = 0, 0.0
+	if verbose {
+		fmt.Print("[Progress] |")
+	}
+	for lss.iterations < lss.numIterations && !lss.cancelled() {
+		lss.simulateMining(iterationSeed(lss.seed, lss.iterations))
+		if verbose {
+			printProgessUpdate(lss.iterations, divisor)
+		}
+		lss.iterations++
+		if lss.progress != nil {
+			lss.progress(lss.iterations)
+		}
+
+		if lss.monitor != nil {
+			lss.monitor.endIteration()
+			if lss.monitor.shouldStop(time.Since(start)) {
+				break
+			}
+		}
+	}
+	if verbose {
+		fmt.Println("|")
+	}
+
+	if lss.monitor != nil {
+		if verbose {
+			lss.monitor.PrintSummary(lss.iterations)
+		}
+		lss.monitor.Reset()
+	}
+
+	if verbose {
+		lss.printAbstained()
+		lss.printMinerStats()
+	}
+
+	lss.results = newResults(lss.iterations, lss.spikes, lss.summary, lss.sampler, lss.abstained)
+}
+
+/**
+ * Prints the number of users of each spike that did not transact because of
+ * the fee rates, for `TxnClass`es with `Bidding`.
+ */
+func (lss *LoadSpikeSimulation) printAbstained() {
+	for i, spike := range lss.spikes {
+		if lss.classes[spike.class].Bidding == nil {
+			continue
+		}
+		fmt.Println("[Bidding]: users priced out during spike", i)
+		fmt.Println(fmt.Sprintf("     %d abstained", lss.abstained[i]))
+	}
+}
+
+/**
+ * Prints the number of blocks mined empty by `SPVMining`, the orphaned
+ * blocks and reorgs caused by `Propagation`, the blocks that paid a
+ * `Penalty`, and the number of blocks found by each named `Miner` and their
+ * mean size.
+ */
+func (lss *LoadSpikeSimulation) printMinerStats() {
+	if lss.penalty != nil {
+		mean := 0.0
+		if lss.penalized > 0 {
+			mean = lss.penalties / float64(lss.penalized)
+		}
+		fmt.Println("[Penalty]: blocks larger than the maximum block size")
+		fmt.Println(fmt.Sprintf("     %d penalized, mean penalty %.0f satoshis", lss.penalized, mean))
+	}
+	if lss.propagation != nil {
+		fmt.Println("[Propagation]: blocks orphaned by forks")
+		fmt.Println(fmt.Sprintf("     %d orphaned", lss.orphaned))
+		for i, reorgs := range lss.reorgs {
+			fmt.Println(fmt.Sprintf("     %d reorgs of depth %d", reorgs, i+1))
+		}
+	}
+	if lss.spv != nil {
+		total := int64(0)
+		for _, blocks := range lss.minerBlocks {
+			total += blocks
+		}
+		fmt.Println("[SPVMining]: blocks mined empty before validating the previous block")
+		fmt.Println(fmt.Sprintf("     %d of %d blocks (%.2f%%)", lss.spvBlocks, total, 100*float64(lss.spvBlocks)/float64(total)))
+	}
+	if lss.miners[0].Name == "" {
+		return
+	}
+
+	fmt.Println("[Miners]: blocks found")
+	for i, m := range lss.miners {
+		meanSize := 0.0
+		if lss.minerBlocks[i] > 0 {
+			meanSize = lss.minerSizes[i] / float64(lss.minerBlocks[i])
+		}
+		fmt.Println(fmt.Sprintf("     %s: %d blocks, mean size %.0f bytes", m.Name, lss.minerBlocks[i], meanSize))
+	}
+}
+
+/**
+ * Clears the state of every `Logger`
+ */
+func (lss *LoadSpikeSimulation) resetLoggers() {
+	for _, logger := range lss.loggers {
+		logger.Reset()
+	}
+}
+
+/**
+ * Sets the seed from which the random numbers of every iteration are derived.
+ * Simulations with the same seed draw the same block intervals and, for the
+ * same `SpikeProfile`, the same txn arrivals, so configurations can be
+ * compared with common random numbers.  Without a seed, a new one is chosen
+ * for every run.
+ *
+ * @param seed - The seed of the simulation
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseSeed(seed int64) *LoadSpikeSimulation {
+	lss.seed = seed
+	lss.seeded = true
+
+	return lss
+}
+
+/**
+ * @return - The seed used by the most recent run
+ */
+func (lss *LoadSpikeSimulation) Seed() int64 {
+	return lss.seed
+}
+
+/**
+ * @return - The summary of the most recent run, nil before the first run
+ */
+func (lss *LoadSpikeSimulation) Results() *Results {
+	return lss.results
+}
+
+/**
+ * @param seed - The seed of the simulation
+ * @param iteration - The index of the iteration
+ *
+ * @return - The seed from which the iteration draws its random numbers
+ */
+func iterationSeed(seed, iteration int64) int64 {
+	return seed + iteration*ITERATION_SEED_STRIDE
+}
+
+/**
+ * Creates independent random number generators for the txn arrivals and the
+ * blocks of a single iteration.
+ *
+ * @param seed - The seed of the iteration
+ */
+func (lss *LoadSpikeSimulation) reseed(seed int64) {
+	lss.txnRand = rand.New(rand.NewSource(2 * seed))
+	lss.blockRand = rand.New(rand.NewSource(2*seed + 1))
+}
+
+/**
+ * Runs a single unlogged iteration and applies MSER to its backlog to find
+ * the number of blocks before the simulation reaches a steady state.  The
+ * pilot draws from its own seed, so the iterations draw the same random
+ * numbers with or without steady state detection.
+ */
+func (lss *LoadSpikeSimulation) detectSteadyState() {
+	loggers, monitor, summary, sampler := lss.loggers, lss.monitor, lss.summary, lss.sampler
+	detector := &steadyStateDetector{}
+
+	lss.loggers = []Logger{detector}
+	lss.monitor = nil
+	lss.summary = nil
+	lss.sampler = nil
+	lss.detectedBlocks = 0
+	lss.simulateMining(lss.seed ^ PILOT_SEED_SALT)
+
+	lss.loggers, lss.monitor, lss.summary, lss.sampler = loggers, monitor, summary, sampler
+	lss.detectedBlocks = detector.truncation()
+}
+
+/**
+ * @return - The number of warm-up blocks, including any detected by MSER
+ */
+func (lss *LoadSpikeSimulation) effectiveWarmUpBlocks() int64 {
+	if lss.detectedBlocks > lss.warmUpBlocks {
+		return lss.detectedBlocks
+	}
+	return lss.warmUpBlocks
+}
+
+/**
+ * @return - The number of iterations performed by the most recent `Run`
+ */
+func (lss *LoadSpikeSimulation) Iterations() int64 {
+	return lss.iterations
+}
+
+/**
+ * Sets the simulations `SpikeProfile`, used by a single default `TxnClass`
+ *
+ * @param sp - The desired `SpikeProfile` for the simulation
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseSpikeProfile(sp *SpikeProfile) *LoadSpikeSimulation {
+	if sp == nil || !sp.valid() {
+		panic("Cannot add invalid SpikeProfile to LoadSpikeSimulation")
+	}
+	// Add spike profile to simulation as the only class
+	lss.useClasses([]TxnClass{defaultTxnClass(sp)})
+
+	return lss
+}
+
+/**
+ * Replaces the `SpikeProfile` with several named `TxnClass`es, each arriving
+ * with its own `SpikeProfile`, sizes and fee rates.  Loggers record every
+ * spike of every class separately, so they must be added afterwards.  The
+ * `txn`s of every class must fit in the simulation's blocks.
+ *
+ * @param classes - The `TxnClass`es of the simulation
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseTxnClasses(classes []TxnClass) *LoadSpikeSimulation {
+	if err := ValidateTxnClasses(classes); err != nil {
+		panic("Cannot add invalid TxnClasses to LoadSpikeSimulation: " + err.Error())
+	}
+	for i := range classes {
+		if err := classes[i].fits(lss.blockSize); err != nil {
+			panic("Cannot add TxnClasses to LoadSpikeSimulation: " + err.Error())
+		}
+	}
+	lss.useClasses(append([]TxnClass{}, classes...))
+
+	return lss
+}
+
+/**
+ * Sets the simulation's `classes` and numbers their spikes.
+ */
+func (lss *LoadSpikeSimulation) useClasses(classes []TxnClass) {
+	lss.classes = classes
+	lss.spikes = classSpikes(classes)
+}
+
+/**
+ * Replaces the single fee maximizing miner with several `Miner`s, each
+ * finding a share of the blocks with its own template policy.  Must be
+ * called before adding an `AnalyticalLogger`.
+ *
+ * @param miners - The `Miner`s of the simulation
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseMiners(miners []Miner) *LoadSpikeSimulation {
+	if err := ValidateMiners(miners); err != nil {
+		panic("Cannot add invalid Miners to LoadSpikeSimulation: " + err.Error())
+	}
+	lss.miners = append([]Miner{}, miners...)
+
+	return lss
+}
+
+/**
+ * Enables `SPVMining`: blocks found within `window` seconds of the previous
+ * block are empty with probability `probability`.  Must be called before
+ * adding an `AnalyticalLogger`.
+ *
+ * @param window - The time in seconds miners take to validate a block
+ * @param probability - The probability that a block found before the
+ *                      previous block is validated is empty
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseSPVMining(window, probability float64) *LoadSpikeSimulation {
+	spv := &SPVMining{Window: window, Probability: probability}
+	if err := spv.Validate(); err != nil {
+		panic("Cannot use invalid SPVMining in LoadSpikeSimulation: " + err.Error())
+	}
+	lss.spv = spv
+
+	return lss
+}
+
+/**
+ * Enables the `Propagation` delay of blocks, which produces orphaned blocks
+ * and reorgs.  Must be called before adding an `AnalyticalLogger`.
+ *
+ * @param latency - The time in seconds every block takes to propagate
+ * @param secondsPerMB - The additional time per million bytes of the block
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UsePropagation(latency, secondsPerMB float64) *LoadSpikeSimulation {
+	p := &Propagation{Latency: latency, SecondsPerMB: secondsPerMB}
+	if err := p.Validate(); err != nil {
+		panic("Cannot use invalid Propagation in LoadSpikeSimulation: " + err.Error())
+	}
+	lss.propagation = p
+
+	return lss
+}
+
+/**
+ * Sets the `BlockSizePolicy` deciding the maximum size of each block, which
+ * starts every iteration from the simulation's block size.  Must be called
+ * before adding an `AnalyticalLogger`.
+ *
+ * @param p - The `BlockSizePolicy` to evaluate
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseBlockSizePolicy(p BlockSizePolicy) *LoadSpikeSimulation {
+	if p == nil {
+		panic("Cannot use nil BlockSizePolicy in LoadSpikeSimulation")
+	}
+	lss.sizePolicy = p
+
+	return lss
+}
+
+/**
+ * Enables the `Penalty`: fee maximizing miners may exceed the maximum block
+ * size, up to `maxFactor` times it, by forfeiting part of the block reward.
+ * Must be called before adding an `AnalyticalLogger`.
+ *
+ * @param kind - How the penalty grows with the excess size, `quadratic` or
+ *               `linear`
+ * @param reward - The block reward in satoshis scaling the penalty
+ * @param maxFactor - The largest block size as a multiple of the maximum
+ *                    block size
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UsePenalty(kind string, reward, maxFactor float64) *LoadSpikeSimulation {
+	p := &Penalty{Kind: kind, Reward: reward, MaxFactor: maxFactor}
+	if err := p.Validate(); err != nil {
+		panic("Cannot use invalid Penalty in LoadSpikeSimulation: " + err.Error())
+	}
+	lss.penalty = p
+
+	return lss
+}
+
+/**
+ * Prints the hashrate share and policy of each named `Miner`.
+ */
+func (lss *LoadSpikeSimulation) printMiners() {
+	if lss.miners[0].Name == "" {
+		return
+	}
+
+	total := 0.0
+	for _, m := range lss.miners {
+		total += m.Hashrate
+	}
+	for _, m := range lss.miners {
+		fmt.Println("[Miner]", m.Name)
+		fmt.Println(fmt.Sprintf("     hashrate: %.1f%%, policy: %s", 100*m.Hashrate/total, m.Policy))
+		if m.SoftLimit > 0.0 {
+			fmt.Println("     soft limit:", m.SoftLimit)
+		}
+	}
+}
+
+/**
+ * Prints the `SpikeProfile` of each `TxnClass`, and the mean size and fee
+ * rate of named classes.
+ */
+func (lss *LoadSpikeSimulation) printClasses() {
+	for _, class := range lss.classes {
+		if class.Name == "" {
+			fmt.Println("[SpikeProfile]")
+		} else {
+			fmt.Println("[TxnClass]", class.Name)
+			fmt.Println(fmt.Sprintf("     mean size: %.1f bytes, mean fee rate: %.2f sat/byte",
+				class.Size.MeanValue(), class.FeeRate.MeanValue()))
+		}
+		class.SpikeProfile.PrintProfile()
+	}
+}
+
+/**
+ * Enables adaptive stopping.  After each iteration the confidence interval of
+ * the `quantile` confirmation time is computed for every spike with load,
+ * counting unconfirmed `txn`s at their age, and the simulation stops once
+ * each spike has `MIN_ADAPTIVE_ITERATIONS` samples and a half-width within
+ * `precision` of its mean, or `maxDuration` has elapsed.  `numIterations` becomes the maximum number of
+ * iterations.  Must be called after setting a `SpikeProfile`.
+ *
+ * @param quantile - The confirmation time quantile to track, e.g. 0.95
+ * @param precision - The target relative half-width, e.g. 0.01 for 1%
+ * @param maxDuration - The maximum wall clock time to run, 0 for no limit
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseAdaptiveStopping(quantile, precision float64, maxDuration time.Duration) *LoadSpikeSimulation {
+	if len(lss.classes) == 0 {
+		panic("Cannot use adaptive stopping without first setting a SpikeProfile")
+	}
+	if quantile <= 0.0 || quantile > 1.0 || precision < 0.0 {
+		panic("Invalid adaptive stopping parameters")
+	}
+
+	loads := make([]float64, len(lss.spikes))
+	for i, spike := range lss.spikes {
+		loads[i] = spike.Load
+	}
+	lss.monitor = newConvergenceMonitor(loads, quantile, precision, maxDuration)
+
+	return lss
+}
+
+/**
+ * Sets a warm-up period during which `txn`s are simulated but not logged.  A
+ * `txn` is excluded if it arrives before the `blocks`-th block is mined or
+ * before `seconds` have elapsed, whichever is later.
+ *
+ * @param blocks - The number of warm-up blocks
+ * @param seconds - The warm-up time in seconds
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseWarmUp(blocks int64, seconds float64) *LoadSpikeSimulation {
+	if blocks < 0 || blocks > lss.numBlocks || seconds < 0.0 {
+		panic("Invalid warm-up period")
+	}
+
+	lss.warmUpBlocks = blocks
+	lss.warmUpSeconds = seconds
+
+	return lss
+}
+
+/**
+ * Stops the simulation after the current iteration once `ctx` is done.  The
+ * results then summarize the completed iterations.
+ *
+ * @param ctx - The `context.Context` that cancels the simulation
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseContext(ctx context.Context) *LoadSpikeSimulation {
+	lss.ctx = ctx
+
+	return lss
+}
+
+/**
+ * Calls `progress` with the number of completed iterations after every
+ * iteration, on the routine running the simulation.
+ *
+ * @param progress - The progress callback
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseProgress(progress func(iterations int64)) *LoadSpikeSimulation {
+	lss.progress = progress
+
+	return lss
+}
+
+/**
+ * Sets the `FeeEstimator` asked for the fee rates of `TxnClass`es with a
+ * `FeeTarget`.  Without one, these classes use a `BucketFeeEstimator`.
+ *
+ * @param fe - The `FeeEstimator` to evaluate
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseFeeEstimator(fe FeeEstimator) *LoadSpikeSimulation {
+	if fe == nil {
+		panic("Cannot use nil FeeEstimator in LoadSpikeSimulation")
+	}
+	lss.estimator = fe
+
+	return lss
+}
+
+/**
+ * @return - Whether the simulation's context has been cancelled
+ */
+func (lss *LoadSpikeSimulation) cancelled() bool {
+	return lss.ctx != nil && lss.ctx.Err() != nil
+}
+
+/**
+ * Enables automatic steady state detection.  Before each `Run`, a pilot
+ * iteration picks the number of warm-up blocks with the MSER-5 rule, which is
+ * used if it exceeds the configured warm-up.
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) UseSteadyStateDetection() *LoadSpikeSimulation {
+	lss.steadyState = true
+
+	return lss
+}
+
+/**
+ * Defines an interface for logging `txn`s and retrieving the outputs to be
+ * written to files.
+ */
+type Logger interface {
+	FilePrefix() string
+	FileExtension() string
+	Log( /* blockTimestamp */ float64, txn)
+	Outputs() []string
+	Reset()
+}
+
+/**
+ * Optional interface for `Logger`s that observe `txn`s entering the `mempool`.
+ */
+type ArrivalLogger interface {
+	LogArrival(txn, *mempool)
+}
+
+/**
+ * Optional interface for `Logger`s that observe each mined block.
+ */
+type BlockLogger interface {
+	LogBlock(block, *mempool)
+}
+
+/**
+ * Optional interface for `Logger`s that need to know when an iteration ends.
+ */
+type IterationLogger interface {
+	EndIteration( /* endTimestamp */ float64, *mempool)
+}
+
+/**
+ * Optional interface for `Logger`s that observe the batches still open at the
+ * end of an iteration, before `EndIteration`.
+ */
+type OpenBatchLogger interface {
+	LogOpenBatch(txn)
+}
+
+/**
+ * Optional interface for `Logger`s with one output per `TxnClass` rather than
+ * one per spike.
+ */
+type ClassLogger interface {
+	ByClass() bool
+}
+
+/**
+ * Adds a unique `TimeSeriesLogger` to the simulation's `loggers`
+ *
+ * @param prefix - The file prefix for writing the output file
+ * @param secsPerBucket - The width of each time bucket in seconds
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddTimeSeriesLogger(prefix string, secsPerBucket float64) *LoadSpikeSimulation {
+	if len(lss.classes) == 0 {
+		panic("Cannot add TimeSeriesLogger without first setting a SpikeProfile")
+	}
+	if secsPerBucket <= 0.0 {
+		panic("Cannot add TimeSeriesLogger with non-positive bucket width")
+	}
+
+	// Append logger to loggers
+	lss.loggers = append(lss.loggers, newTimeSeriesLogger(prefix, secsPerBucket, len(lss.classes)))
+
+	return lss
+}
+
+/**
+ * Adds a unique `CumulativeLogger` to the simulation's `loggers`
+ *
+ * @param prefix - The file prefix for writing the output file
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddCumulativeLogger(prefix string) *LoadSpikeSimulation {
+	return lss.addCumulativeLogger(prefix, false)
+}
+
+/**
+ * Adds a unique `CumulativeLogger` to the simulation's `loggers` whose output
+ * includes a Kaplan-Meier estimate accounting for unconfirmed `txn`s.
+ *
+ * @param prefix - The file prefix for writing the output file
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddKaplanMeierLogger(prefix string) *LoadSpikeSimulation {
+	return lss.addCumulativeLogger(prefix, true)
+}
+
+/**
+ * Builds a `CumulativeLogger` and appends it to the simulation's `loggers`
+ */
+func (lss *LoadSpikeSimulation) addCumulativeLogger(prefix string, kaplanMeier bool) *LoadSpikeSimulation {
+	// Append logger to loggers
+	lss.loggers = append(lss.loggers, lss.newCumulativeLogger(prefix, kaplanMeier))
+
+	return lss
+}
+
+/**
+ * Builds a `CumulativeLogger` with a plot for each spike.
+ *
+ * @param prefix - The file prefix for writing the output file
+ * @param kaplanMeier - Whether to include a Kaplan-Meier estimate
+ *
+ * @return - The new `CumulativeLogger`
+ */
+func (lss *LoadSpikeSimulation) newCumulativeLogger(prefix string, kaplanMeier bool) *CumulativeLogger {
+	if len(lss.classes) == 0 {
+		panic("Cannot add CumulativeLogger without first setting a SpikeProfile")
+	}
+
+	// Create a plot record for each spike
+	numPlots := len(lss.spikes)
+	plots := make([]*cumulativePlot, numPlots)
+	for i := range plots {
+		plots[i] = newCumulativePlot()
+	}
+
+	// Build logger
+	return &CumulativeLogger{
+		plots:       plots,
+		filePrefix:  prefix,
+		kaplanMeier: kaplanMeier,
+	}
+}
+
+/**
+ * Adds an `AnalyticalLogger` to the simulation's `loggers`, writing the
+ * analytical confirmation time CDF of the constant load.
+ *
+ * @param prefix - The file prefix for writing the output file
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddAnalyticalLogger(prefix string) *LoadSpikeSimulation {
+	if len(lss.classes) == 0 {
+		panic("Cannot add AnalyticalLogger without first setting a SpikeProfile")
+	}
+
+	sp, err := analyticalProfile(lss.classes)
+	if err != nil {
+		panic("Cannot add AnalyticalLogger: " + err.Error())
+	}
+	if err := analyticalMining(lss.miners, lss.spv, lss.propagation); err != nil {
+		panic("Cannot add AnalyticalLogger: " + err.Error())
+	}
+	if _, fixed := lss.sizePolicy.(*FixedBlockSize); (lss.sizePolicy != nil && !fixed) || lss.penalty != nil {
+		panic("Cannot add AnalyticalLogger: analytical baseline requires a fixed block size")
+	}
+	al, err := newAnalyticalLogger(prefix, lss.blockSize, sp)
+	if err != nil {
+		panic("Cannot add AnalyticalLogger: " + err.Error())
+	}
+	lss.loggers = append(lss.loggers, al)
+
+	return lss
+}
+
+/**
+ * Adds a `ConfirmationDepthLogger` to the simulation's `loggers` for each of
+ * the `depths`.  The depth is appended to the file prefix, e.g. `<prefix>-k6`.
+ *
+ * @param prefix - The file prefix for writing the output files
+ * @param depths - The numbers of confirmations to measure, 1 is equivalent
+ *                 to `AddCumulativeLogger`
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddConfirmationDepthLogger(prefix string, depths []int64) *LoadSpikeSimulation {
+	for _, depth := range depths {
+		if depth < 1 {
+			panic("Cannot add ConfirmationDepthLogger with depth less than 1")
+		}
 
-	// Calculate divisor for progress bar
-	divisor := lss.numIterations / 100
-	if divisor == 0 {
-		divisor = 1
+		cl := lss.newCumulativeLogger(fmt.Sprintf("%s-k%d", prefix, depth), false)
+		lss.loggers = append(lss.loggers, newConfirmationDepthLogger(cl, depth))
 	}
 
-	// Run simulation
-	fmt.Print("[Progress] |")
-	for i := int64(0); i < lss.numIterations; i++ {
-		lss.simulateMining()
-		printProgessUpdate(i, divisor)
-	}
-	fmt.Println("|")
+	return lss
+}
 
-	lss.outputResults()
+/**
+ * Adds a `PaymentLogger` to the simulation's `loggers`, writing cumulative
+ * files with the prefix `<prefix>-payment`.
+ *
+ * @param prefix - The file prefix for writing the output files
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddPaymentLogger(prefix string) *LoadSpikeSimulation {
+	cl := lss.newCumulativeLogger(prefix+"-payment", false)
+	lss.loggers = append(lss.loggers, newPaymentLogger(cl))
 
-	// Reset loggers in case the simulation is reused
-	for _, logger := range lss.loggers {
-		logger.Reset()
-	}
+	return lss
 }
 
 /**
- * Sets the simulations `spikeProfile`
+ * Adds a `FeeEstimateLogger` to the simulation's `loggers`, evaluating the
+ * fee rates of the `TxnClass`es with a `FeeTarget`.
  *
- * @param sp - The desired `SpikeProfile` for the simulation
+ * @param prefix - The file prefix for writing the output files
  *
  * @return - The updated `LoadSpikeSimulation`
  */
-func (lss *LoadSpikeSimulation) UseSpikeProfile(sp *SpikeProfile) *LoadSpikeSimulation {
-	if sp == nil || !sp.valid() {
-		panic("Cannot add invalid SpikeProfile to LoadSpikeSimulation")
+func (lss *LoadSpikeSimulation) AddFeeEstimateLogger(prefix string) *LoadSpikeSimulation {
+	if !useFeeEstimates(lss.classes) {
+		panic("Cannot add FeeEstimateLogger without a TxnClass with a FeeTarget")
 	}
-	// Add spike profile to simulation
-	lss.spikeProfile = sp
+
+	lss.loggers = append(lss.loggers, newFeeEstimateLogger(prefix, lss.classes))
 
 	return lss
 }
 
 /**
- * Defines an interface for logging `txn`s and retrieving the outputs to be
- * written to files.
+ * Adds a unique `BacklogLogger` to the simulation's `loggers`
+ *
+ * @param prefix - The file prefix for writing the output file
+ * @param secsPerBucket - The width of each time bucket in seconds
+ *
+ * @return - The updated `LoadSpikeSimulation`
  */
-type Logger interface {
-	FilePrefix() string
-	FileExtension() string
-	Log( /* blockTimestamp */ float64, txn)
-	Outputs() []string
-	Reset()
+func (lss *LoadSpikeSimulation) AddBacklogLogger(prefix string, secsPerBucket float64) *LoadSpikeSimulation {
+	if len(lss.classes) == 0 {
+		panic("Cannot add BacklogLogger without first setting a SpikeProfile")
+	}
+	if secsPerBucket <= 0.0 {
+		panic("Cannot add BacklogLogger with non-positive bucket width")
+	}
+
+	// Append logger to loggers
+	lss.loggers = append(lss.loggers, newBacklogLogger(prefix, secsPerBucket, len(lss.classes)))
+
+	return lss
 }
 
 /**
- * Adds a unique `TimeSeriesLogger` to the simulation's `loggers`
+ * Adds a unique `BlockStatsLogger` to the simulation's `loggers`
  *
  * @param prefix - The file prefix for writing the output file
  *
  * @return - The updated `LoadSpikeSimulation`
  */
-func (lss *LoadSpikeSimulation) AddTimeSeriesLogger(prefix string) *LoadSpikeSimulation {
-	// Create new time series logger
-	tsLogger := &TimeSeriesLogger{
-		plot:          newTimeSeriesPlot(),
-		secsPerBucket: 60.0,
-		filePrefix:    prefix,
+func (lss *LoadSpikeSimulation) AddBlockStatsLogger(prefix string) *LoadSpikeSimulation {
+	if len(lss.classes) == 0 {
+		panic("Cannot add BlockStatsLogger without first setting a SpikeProfile")
 	}
 
 	// Append logger to loggers
-	lss.loggers = append(lss.loggers, tsLogger)
+	lss.loggers = append(lss.loggers, newBlockStatsLogger(prefix, len(lss.classes)))
 
 	return lss
 }
 
 /**
- * Adds a unique `CumulativeLogger` to the simulation's `loggers`
+ * Adds a `TraceLogger` to the simulation's `loggers`, writing a sample of
+ * individual `txn`s to `path`.
  *
- * @param prefix - The file prefix for writing the output file
+ * @param path - The path of the trace file
+ * @param sampleRate - The fraction of `txn`s to record, in (0, 1]
  *
  * @return - The updated `LoadSpikeSimulation`
  */
-func (lss *LoadSpikeSimulation) AddCumulativeLogger(prefix string) *LoadSpikeSimulation {
-	if lss.spikeProfile == nil {
-		panic("Cannot add CumulativeLogger without first setting a SpikeProfile")
+func (lss *LoadSpikeSimulation) AddTraceLogger(path string, sampleRate float64) *LoadSpikeSimulation {
+	if len(lss.classes) == 0 {
+		panic("Cannot add TraceLogger without first setting a SpikeProfile")
 	}
-
-	// Create a plot record for each spike
-	numPlots := len(lss.spikeProfile.Spikes)
-	plots := make([]*cumulativePlot, numPlots)
-	for i := range plots {
-		plots[i] = newCumulativePlot()
+	if sampleRate <= 0.0 || sampleRate > 1.0 {
+		panic("Cannot add TraceLogger with sample rate outside of (0, 1]")
 	}
 
-	// Build logger
-	cLogger := &CumulativeLogger{
-		plots:      plots,
-		filePrefix: prefix,
+	// Append logger to loggers
+	lss.loggers = append(lss.loggers, newTraceLogger(path, sampleRate, lss.spikes, lss.Seed))
+
+	return lss
+}
+
+/**
+ * Adds a `MetricsLogger` to the simulation's `loggers`.  The caller serves
+ * `ml` to expose the metrics while the simulation runs.
+ *
+ * @param ml - The `MetricsLogger` to update
+ *
+ * @return - The updated `LoadSpikeSimulation`
+ */
+func (lss *LoadSpikeSimulation) AddMetricsLogger(ml *MetricsLogger) *LoadSpikeSimulation {
+	if ml == nil {
+		panic("Cannot add nil MetricsLogger to LoadSpikeSimulation")
 	}
+	ml.configure(lss.numIterations, lss.spikes)
 
 	// Append logger to loggers
-	lss.loggers = append(lss.loggers, cLogger)
+	lss.loggers = append(lss.loggers, ml)
 
 	return lss
 }
 
-
 /**
  * Obtains the outputs from each logger and writes them to their specified file.
+ * Outputs are named after their spike, or after the first spike of their
+ * class for a `ClassLogger`.
  */
 func (lss *LoadSpikeSimulation) outputResults() {
+	// Index of the first spike of each class
+	firstSpikes := []int{}
+	for i, spike := range lss.spikes {
+		if spike.spike == 0 {
+			firstSpikes = append(firstSpikes, i)
+		}
+	}
+
 	// Create output for each logger
 	for _, logger := range lss.loggers {
 		// Create file prefix to dump results
 		filePrefix := logger.FilePrefix()
+		cl, byClass := logger.(ClassLogger)
+		byClass = byClass && cl.ByClass()
 
 		// Get each file contents and write to file
 		for i, fileContents := range logger.Outputs() {
+			spike := lss.spikes[i]
+			if byClass {
+				spike = lss.spikes[firstSpikes[i]]
+			}
+
 			// Create full filename
 			filename := filePrefix
-			filename += "-" + lss.spikeProfile.Spikes[i].String()
-			filename += fmt.Sprintf("-%d-%d", lss.numBlocks, lss.numIterations)
+			filename += "-" + spike.String()
+			filename += fmt.Sprintf("-%d-%d", lss.numBlocks, lss.iterations)
 			filename += "." + logger.FileExtension()
 			// Write file contents to filename
 			err := ioutil.WriteFile(filename, []byte(fileContents), 0644)
@@ -202,8 +1031,12 @@ func (lss *LoadSpikeSimulation) outputResults() {
 /**
  * Spawns a routine to produce `txn`s, which are passed through a channel and
  * consumed on the main routine when they are added to a block.
+ *
+ * @param seed - The seed from which the iteration draws its random numbers
  */
-func (lss *LoadSpikeSimulation) simulateMining() {
+func (lss *LoadSpikeSimulation) simulateMining(seed int64) {
+	lss.reseed(seed)
+
 	pendingTxnChan := make(chan txn)
 	readyChan := make(chan bool)
 	blockNumChan := make(chan int64)
@@ -215,94 +1048,532 @@ func (lss *LoadSpikeSimulation) simulateMining() {
 }
 
 /**
- * Produces transactions with timestamps drawn from a poisson distribution.  The
- * distribution is updated according to the `SpikeProfile`.  Transactions are
- * passed back through channels to be consumed in `createBlock`.
+ * Produces transactions with timestamps drawn from a poisson distribution.  Each
+ * `TxnClass` arrives independently and its distribution is updated according
+ * to its `SpikeProfile`.  Transactions are passed back through channels, in
+ * order of arrival, to be consumed in `createBlock`.
+ *
+ * A class without load keeps no pending `txn`.  Once its load becomes positive
+ * it starts arriving after the most recently sent `txn`, so at least one
+ * class must have load for arrivals to continue.
  *
  * @param pendingTxnChan - Channel for sending pending `txn`s to be consumed.
  * @param blockNumChan - Channel for receiving the current simultion's progress.
  *                       Used to determine the current load and spike index.
  */
 func (lss *LoadSpikeSimulation) createTxns(pendingTxnChan chan txn, readyChan chan bool, blockNumChan chan int64) {
-	var currentTPS float64
-	var currentSpikeIndex int
-	var currentTxnTimestamp float64
+	numClasses := len(lss.classes)
+	currentTPS := make([]float64, numClasses)
+	currentSpikeIndex := make([]int, numClasses)
+
+	// Index of the first spike of each class
+	firstSpikes := make([]int, numClasses)
+	for i := len(lss.spikes) - 1; i >= 0; i-- {
+		firstSpikes[lss.spikes[i].class] = i
+	}
+
+	// The next txn of each class, and the class of the most recently sent txn
+	next := make([]txn, numClasses)
+	sent := 0
 
 	for {
 		select {
 		case i, ok := <-blockNumChan:
-			// Finished mining, simulation is complete
+			// Finished mining, simulation is complete.  The batches of the
+			// other classes that are still open are sent to be censored
 			if !ok {
+				for k := range next {
+					if k != sent && next[k].payments != nil {
+						pendingTxnChan <- next[k]
+					}
+				}
 				close(pendingTxnChan)
 				close(readyChan)
 				return
 			}
 
 			// Use percentage to get current tps (for poisson process) and spike
-			// index (for logging)
+			// index (for logging) of each class
 			percent := float64(i) / float64(lss.numBlocks)
-			// Percentage of BITCOIN_MAX_TPS
-			currentLoad := lss.spikeProfile.currentLoad(percent)
-			currentTPS = currentLoad * BITCOIN_MAX_TPS
-			// Determines which log do eventually record the transaction under
-			currentSpikeIndex = lss.spikeProfile.currentSpikeIndex(percent)
+			for k := range lss.classes {
+				sp := &lss.classes[k].SpikeProfile
+				// Percentage of BITCOIN_MAX_TPS
+				currentTPS[k] = sp.currentLoad(percent) * BITCOIN_MAX_TPS
+				// Determines which log do eventually record the transaction under
+				currentSpikeIndex[k] = firstSpikes[k] + sp.currentSpikeIndex(percent)
+			}
 
-			// If starting new iteration, reset timestamp and send first txn
+			// If starting new iteration, reset timestamps and send first txn
 			if i == 0 {
-				currentTxnTimestamp = drawFromPoisson(currentTPS)
-				pendingTxnChan <- txn{currentTxnTimestamp, currentSpikeIndex}
+				for k := range next {
+					next[k] = lss.drawTxn(k, 0.0, currentTPS[k], currentSpikeIndex[k])
+				}
+				sent = sendEarliest(pendingTxnChan, next)
+				continue
+			}
+
+			// Classes whose load became positive start arriving
+			for k := range next {
+				if math.IsInf(next[k].time, 1) && currentTPS[k] > 0.0 {
+					next[k] = lss.drawTxn(k, next[sent].time, currentTPS[k], currentSpikeIndex[k])
+				}
 			}
 		case _ = <-readyChan:
-			// Create and broadcast next txn
-			currentTxnTimestamp += drawFromPoisson(currentTPS)
-			pendingTxnChan <- txn{currentTxnTimestamp, currentSpikeIndex}
+			// Replace the sent txn and broadcast the next txn
+			next[sent] = lss.drawTxn(sent, next[sent].time, currentTPS[sent], currentSpikeIndex[sent])
+			sent = sendEarliest(pendingTxnChan, next)
+		}
+	}
+}
+
+/**
+ * Draws the next `txn` of a class, arriving after `timestamp`.
+ *
+ * @param class - The index of the `TxnClass`
+ * @param timestamp - The arrival time of the previous `txn` of the class
+ * @param tps - The current arrival rate of the class, 0 for no arrivals
+ * @param index - The spike index to record the `txn` under
+ *
+ * @return - The next `txn`
+ */
+func (lss *LoadSpikeSimulation) drawTxn(class int, timestamp, tps float64, index int) txn {
+	interval := drawFromPoissonWith(lss.txnRand.Float64(), tps)
+	if tps <= 0.0 {
+		interval = math.Inf(1)
+	}
+
+	tc := &lss.classes[class]
+	t := txn{
+		time:    timestamp + interval,
+		index:   index,
+		class:   class,
+		size:    lss.drawSize(tc),
+		feeRate: tc.FeeRate.Sample(lss.txnRand),
+	}
+	if tc.Bidding != nil {
+		t.maxFeeRate = tc.Bidding.MaxFeeRate.Sample(lss.txnRand)
+	}
+	if tc.Batching != nil && tps > 0.0 {
+		lss.fillBatch(&t, tc.Batching, tps)
+	}
+
+	return t
+}
+
+/**
+ * Draws the size of a `txn` of the class, redrawing sizes larger than the
+ * simulation's block size.  Sizes that fit draw no further random numbers.
+ *
+ * @param tc - The `TxnClass` of the `txn`
+ *
+ * @return - The size in bytes
+ */
+func (lss *LoadSpikeSimulation) drawSize(tc *TxnClass) float64 {
+	size := tc.Size.Sample(lss.txnRand)
+	for size > lss.blockSize {
+		size = tc.Size.Sample(lss.txnRand)
+	}
+	return size
+}
+
+/**
+ * Accumulates payments into a batch opened by the payment arriving at
+ * `t.time`, until the batch interval ends or the batch is full, and
+ * broadcasts it at that time.  A batch is full once it holds `MaxPayments`
+ * payments, or once another output would not fit in a block.  The payments
+ * of a batch arrive at the load of the class when the batch opens.
+ *
+ * @param t - The first payment of the batch, updated to the batch `txn`
+ * @param b - The batching parameters of the class
+ * @param tps - The current arrival rate of payments
+ */
+func (lss *LoadSpikeSimulation) fillBatch(t *txn, b *Batching, tps float64) {
+	t.payments = []float64{t.time}
+	deadline := t.time + b.Interval
+	full := func() bool {
+		if b.MaxPayments > 0 && len(t.payments) >= b.MaxPayments {
+			return true
+		}
+		return t.size+float64(len(t.payments))*b.OutputSize > lss.blockSize
+	}
+	for !full() {
+		// Later payments are memoryless, the next batch draws its own
+		arrival := t.payments[len(t.payments)-1] + drawFromPoissonWith(lss.txnRand.Float64(), tps)
+		if arrival > deadline {
+			break
+		}
+		t.payments = append(t.payments, arrival)
+	}
+
+	if full() {
+		t.time = t.payments[len(t.payments)-1]
+	} else {
+		t.time = deadline
+	}
+	t.size += float64(len(t.payments)-1) * b.OutputSize
+}
+
+/**
+ * Sends the earliest of the `next` `txn`s, the lowest class first on ties.
+ *
+ * @param pendingTxnChan - Channel for sending pending `txn`s to be consumed
+ * @param next - The next `txn` of each class
+ *
+ * @return - The class of the sent `txn`
+ */
+func sendEarliest(pendingTxnChan chan txn, next []txn) int {
+	earliest := 0
+	for k := 1; k < len(next); k++ {
+		if next[k].time < next[earliest].time {
+			earliest = k
 		}
 	}
+
+	pendingTxnChan <- next[earliest]
+	return earliest
+}
+
+/**
+ * `block`
+ *
+ * Records the outcome of mining a single block, including the index of the
+ * `Miner` that found it, whether it was mined empty by `SPVMining` and the
+ * `Penalty` its miner paid to exceed `maxSize`.
+ */
+type block struct {
+	height    int64
+	timestamp float64
+	interval  float64
+	maxSize   float64
+	size      float64
+	numTxns   int64
+	txns      []txn
+	miner     int
+	spv       bool
+	penalty   float64
+}
+
+/**
+ * @return - Whether the block has no room left for another `txn`
+ */
+func (b block) full() bool {
+	return b.maxSize-b.size < BITCOIN_TRANSACTION_SIZE
+}
+
+/**
+ * @return - The lowest fee rate of the included `txn`s in satoshis per byte,
+ *           and false if the block is empty
+ */
+func (b block) minFeeRate() (float64, bool) {
+	if len(b.txns) == 0 {
+		return 0.0, false
+	}
+
+	rate := math.Inf(1)
+	for _, t := range b.txns {
+		rate = math.Min(rate, t.feeRate)
+	}
+	return rate, true
 }
 
 /**
- * Consumes `txn`s produced by `createTxn` and logs each one to the simulations
+ * Consumes `txn`s produced by `createTxn`, moving them into the `mempool` as
+ * they arrive.  Each block is found by one of the `Miner`s, includes the
+ * `txn`s chosen by its template policy and logs them to the simulations
  * `loggers`.
  *
  * @param pendingTxnChan - Channel for receiving pending `txn`s to be consumed
+ * @param readyChan - Channel for signaling when `createTxn` should send the next `txn`
  * @param blockNumChan - Channel for sending the current simultion's progress to
  *                       `createTxns`. Used to determine the current load and
  *                       spike index.
  */
 func (lss *LoadSpikeSimulation) createBlocks(pendingTxnChan chan txn, readyChan chan bool, blockNumChan chan int64) {
 	currentBlockTimestamp := float64(0)
+	// Fee rates observed by bidding users, none before the first block
+	market := make([]float64, len(lss.classes))
+	if lss.estimator != nil {
+		lss.estimator.Reset()
+	}
+	if lss.sizePolicy != nil {
+		lss.sizePolicy.Reset()
+	}
 
-	var t txn
-	usePreviousTxn := false
-	for i := int64(0); i < lss.numBlocks; i++ {
-		blockNumChan <- i
+	// Txns arriving before `warmUpEnd` are not logged, the end of a warm-up in
+	// blocks is unknown until the block is mined
+	warmUpBlocks := lss.effectiveWarmUpBlocks()
+	warmUpEnd := lss.warmUpSeconds
+	if warmUpBlocks > 0 {
+		warmUpEnd = math.Inf(1)
+	}
+
+	// The observed chain, and the competing branch while the chain is forked
+	chain := &branch{pool: newMempool()}
+	var fork *branch
+
+	var next txn
+	// Moves every txn that arrives before `timestamp` into the mempool of
+	// each branch
+	arrive := func(timestamp float64) {
+		for next.time < timestamp {
+			lss.estimateFee(&next)
+			if !lss.bid(&next, market) {
+				if lss.abstained != nil && next.time >= warmUpEnd {
+					lss.abstained[next.index]++
+				}
+
+				readyChan <- true
+				next = <-pendingTxnChan
+				continue
+			}
+			chain.pool.add(next)
+			if fork != nil {
+				fork.pool.add(next)
+			}
+			lss.logArrival(next, chain.pool)
+
+			readyChan <- true
+			next = <-pendingTxnChan
+		}
+	}
 
-		currentBlockTimestamp += drawFromPoisson(BITCOIN_BLOCK_RATE)
-		remainingBlockSize := lss.blockSize
+	// Logs a block of the winning chain and its txns
+	confirm := func(b block) {
+		for _, t := range b.txns {
+			if t.time >= warmUpEnd {
+				lss.logTxn(b.timestamp, t)
+			}
+		}
+		if b.height+1 == warmUpBlocks {
+			warmUpEnd = math.Max(lss.warmUpSeconds, b.timestamp)
+		}
 
-		// Must process `txn` before starting loop if last block was full
-		if usePreviousTxn {
-			remainingBlockSize -= BITCOIN_TRANSACTION_SIZE
-			lss.logTxn(currentBlockTimestamp, t, readyChan)
+		if lss.minerBlocks != nil {
+			lss.minerBlocks[b.miner]++
+			lss.minerSizes[b.miner] += b.size
+			if b.spv {
+				lss.spvBlocks++
+			}
+			if b.penalty > 0.0 {
+				lss.penalized++
+				lss.penalties += b.penalty
+			}
+		}
+		if lss.estimator != nil {
+			lss.estimator.ObserveBlock(b, chain.pool)
+		}
+		if lss.sizePolicy != nil {
+			lss.sizePolicy.ObserveBlock(b)
+		}
+		lss.observeMarket(chain.pool, market, b.maxSize)
+		lss.logBlock(b, chain.pool)
+	}
 
-			usePreviousTxn = false
+	// Ends a fork, if any, in favor of `winner` and confirms the blocks of the
+	// winning branch
+	resolve := func(winner *branch) {
+		if fork != nil {
+			orphaned, reorg := chain.resolve(fork, winner)
+			depth := 0
+			if reorg {
+				depth = len(orphaned)
+			}
+			lss.recordFork(len(orphaned), depth)
+			fork = nil
 		}
 
-		for t = range pendingTxnChan {
-			// If `txn` belongs in next block or doesn't fit in current block, process
-			// later
-			if t.time >= currentBlockTimestamp || remainingBlockSize < BITCOIN_TRANSACTION_SIZE {
-				usePreviousTxn = true
-				break
+		for _, b := range chain.blocks {
+			confirm(b)
+		}
+		chain.blocks = nil
+	}
+
+	for i := int64(0); i < lss.numBlocks; i++ {
+		blockNumChan <- i
+		// The first txn of an iteration is sent without waiting for `readyChan`
+		if i == 0 {
+			next = <-pendingTxnChan
+		}
+
+		interval := drawFromPoissonWith(lss.blockRand.Float64(), BITCOIN_BLOCK_RATE)
+		currentBlockTimestamp += interval
+		arrive(currentBlockTimestamp)
+
+		// During a fork each block extends either branch with equal
+		// probability
+		extended, other := chain, fork
+		if fork != nil {
+			if lss.blockRand.Float64() < 0.5 {
+				extended, other = fork, chain
 			}
+			interval = currentBlockTimestamp - extended.timestamp
+		}
+		b := lss.mineBlock(extended.pool, i, currentBlockTimestamp, interval)
+
+		offset, forked := lss.propagation.race(b.size, lss.blockRand.Float64)
+		if forked && fork == nil {
+			// The competing block is mined from the same txns and those that
+			// arrive until it is found
+			fork = chain.fork(b)
+			other = fork
+		}
+		extended.extend(b)
 
-			remainingBlockSize -= BITCOIN_TRANSACTION_SIZE
-			lss.logTxn(currentBlockTimestamp, t, readyChan)
+		if forked {
+			// The other branch catches up before the block propagates
+			currentBlockTimestamp += offset
+			arrive(currentBlockTimestamp)
+			c := lss.mineBlock(other.pool, i, currentBlockTimestamp, currentBlockTimestamp-other.timestamp)
+			other.extend(c)
+			continue
+		}
+		resolve(extended)
+	}
+	// A fork still tied at the end of the iteration is resolved at random
+	if fork != nil {
+		if lss.blockRand.Float64() < 0.5 {
+			resolve(fork)
+		} else {
+			resolve(chain)
 		}
 	}
 
-	// Terminates channels in createTxns
+	// Terminates channels in createTxns, after it sends the batches that are
+	// still open
 	close(blockNumChan)
+	open := []txn{}
+	if next.payments != nil {
+		open = append(open, next)
+	}
+	for t := range pendingTxnChan {
+		open = append(open, t)
+	}
+
+	lss.logEndIteration(currentBlockTimestamp, chain.pool.arrivedSince(warmUpEnd), open)
+}
+
+/**
+ * Mines a block found at `timestamp`, `interval` seconds after its parent,
+ * by a `Miner` chosen by hashrate and with its template policy, unless
+ * `SPVMining` leaves it empty.  The maximum size of the `BlockSizePolicy`
+ * is floored at the largest `txn` any class can draw.
+ *
+ * @param pool - The pending `txn`s of the branch the block extends
+ * @param height - The height of the block
+ * @param timestamp - The time the block is found
+ * @param interval - The time in seconds since its parent
+ *
+ * @return - The mined block
+ */
+func (lss *LoadSpikeSimulation) mineBlock(pool *mempool, height int64, timestamp, interval float64) block {
+	maxSize := lss.blockSize
+	if lss.sizePolicy != nil {
+		// Never below the largest txn, which could otherwise not be mined
+		maxSize = math.Max(lss.sizePolicy.MaxBlockSize(height), lss.largestTxnSize())
+	}
+
+	miner := chooseMiner(lss.miners, lss.blockRand.Float64)
+	if lss.spv.validating(interval, lss.blockRand.Float64) {
+		return block{height: height, timestamp: timestamp, interval: interval, maxSize: maxSize, miner: miner, spv: true}
+	}
+
+	included, size := lss.miners[miner].fillBlock(pool, maxSize, lss.penalty)
+	return block{
+		height:    height,
+		timestamp: timestamp,
+		interval:  interval,
+		maxSize:   maxSize,
+		size:      size,
+		numTxns:   int64(len(included)),
+		txns:      included,
+		miner:     miner,
+		penalty:   lss.penalty.cost(size, maxSize),
+	}
+}
+
+/**
+ * @return - The size in bytes of the largest `txn` or batch of any class
+ */
+func (lss *LoadSpikeSimulation) largestTxnSize() float64 {
+	largest := 0.0
+	for i := range lss.classes {
+		largest = math.Max(largest, lss.classes[i].largestSize(lss.blockSize))
+	}
+	return largest
+}
+
+/**
+ * Counts the blocks orphaned when a fork resolves.
+ *
+ * @param orphaned - The number of blocks of the losing branch
+ * @param depth - The number of blocks of the reorg, 0 if the observed branch
+ *                won and nodes kept their chain
+ */
+func (lss *LoadSpikeSimulation) recordFork(orphaned, depth int) {
+	if lss.reorgs == nil {
+		return
+	}
+
+	lss.orphaned += int64(orphaned)
+	if depth == 0 {
+		return
+	}
+	for len(lss.reorgs) < depth {
+		lss.reorgs = append(lss.reorgs, 0)
+	}
+	lss.reorgs[depth-1]++
+}
+
+/**
+ * Replaces the fee rate of an arriving `txn` whose class has a `FeeTarget`
+ * with the estimate of the `FeeEstimator`, if there is one.
+ *
+ * @param t - The arriving `txn`
+ */
+func (lss *LoadSpikeSimulation) estimateFee(t *txn) {
+	target := lss.classes[t.class].FeeTarget
+	if target == 0 || lss.estimator == nil {
+		return
+	}
+
+	if feeRate, ok := lss.estimator.EstimateFee(target); ok {
+		t.feeRate = feeRate
+		t.target = target
+	}
+}
+
+/**
+ * Sets the fee rate of an arriving `txn` whose class has `Bidding` from the
+ * observed `market` fee rates.
+ *
+ * @param t - The arriving `txn`
+ * @param market - The fee rate observed by each class
+ *
+ * @return - Whether the user transacts at the bid fee rate
+ */
+func (lss *LoadSpikeSimulation) bid(t *txn, market []float64) bool {
+	b := lss.classes[t.class].Bidding
+	if b == nil {
+		return true
+	}
+
+	t.feeRate = math.Max(t.feeRate, market[t.class]*(1.0+b.Premium))
+	return t.feeRate <= t.maxFeeRate
+}
+
+/**
+ * Updates the fee rates observed by the classes with `Bidding` after a block.
+ *
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ * @param market - The fee rate observed by each class, updated in place
+ * @param maxSize - The maximum size in bytes of the block
+ */
+func (lss *LoadSpikeSimulation) observeMarket(pool *mempool, market []float64, maxSize float64) {
+	for k := range lss.classes {
+		if b := lss.classes[k].Bidding; b != nil {
+			market[k] = pool.feeRateAtDepth(b.TargetBlocks * maxSize)
+		}
+	}
 }
 
 /**
@@ -311,15 +1582,78 @@ func (lss *LoadSpikeSimulation) createBlocks(pendingTxnChan chan txn, readyChan
  *
  * @param blockTimestamp - Timestamp of the block that recorded `txn`
  * @param t - The `txn` that was consumed
- * @param readyChan - Channel for signaling when `createTxn` should send the next `txn`
  */
-func (lss *LoadSpikeSimulation) logTxn(blockTimestamp float64, t txn, readyChan chan bool) {
+func (lss *LoadSpikeSimulation) logTxn(blockTimestamp float64, t txn) {
 	for _, logger := range lss.loggers {
 		logger.Log(blockTimestamp, t)
 	}
-	readyChan <- true
+	if lss.monitor != nil {
+		lss.monitor.Log(blockTimestamp, t)
+	}
+	if lss.summary != nil {
+		lss.summary.Log(blockTimestamp, t)
+	}
+	if lss.sampler != nil {
+		lss.sampler.Log(blockTimestamp, t)
+	}
+}
+
+/**
+ * Notifies each `ArrivalLogger` that `t` has entered the `mempool`
+ *
+ * @param t - The arriving `txn`
+ * @param pool - The `mempool` after `t` was added
+ */
+func (lss *LoadSpikeSimulation) logArrival(t txn, pool *mempool) {
+	for _, logger := range lss.loggers {
+		if al, ok := logger.(ArrivalLogger); ok {
+			al.LogArrival(t, pool)
+		}
+	}
+}
+
+/**
+ * Notifies each `BlockLogger` that a block has been mined
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (lss *LoadSpikeSimulation) logBlock(b block, pool *mempool) {
+	for _, logger := range lss.loggers {
+		if bl, ok := logger.(BlockLogger); ok {
+			bl.LogBlock(b, pool)
+		}
+	}
 }
 
+/**
+ * Notifies each `IterationLogger` that the current iteration has finished
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ * @param open - The batches that had not been broadcast
+ */
+func (lss *LoadSpikeSimulation) logEndIteration(endTimestamp float64, pool *mempool, open []txn) {
+	for _, logger := range lss.loggers {
+		if ol, ok := logger.(OpenBatchLogger); ok {
+			for _, t := range open {
+				ol.LogOpenBatch(t)
+			}
+		}
+		if il, ok := logger.(IterationLogger); ok {
+			il.EndIteration(endTimestamp, pool)
+		}
+	}
+	if lss.summary != nil {
+		lss.summary.EndIteration(endTimestamp, pool)
+	}
+	if lss.monitor != nil {
+		lss.monitor.EndIteration(endTimestamp, pool)
+	}
+	if lss.sampler != nil {
+		lss.sampler.endIteration()
+	}
+}
 
 /**
  * Prints progress bar `|=========(10)=========(20)======...===|`
@@ -1,6 +1,9 @@
 package bitcoin_load_spike
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestNewLoadSpikeSimulation(t *testing.T) {
 	expectedNumBlocks := int64(1000)
@@ -18,8 +21,8 @@ func TestNewLoadSpikeSimulation(t *testing.T) {
 	if sim.numIterations != expectedNumIterations {
 		t.Error("Expected numIterations to be", expectedNumIterations, ", got", sim.numIterations)
 	}
-	if sim.spikeProfile != nil {
-		t.Error("Expected spikeProfile to be nil, got", sim.spikeProfile)
+	if len(sim.classes) != 0 {
+		t.Error("Expected no classes, got", sim.classes)
 	}
 	if len(sim.loggers) != 0 {
 		t.Error("Expected loggers to have length 0, got", len(sim.loggers))
@@ -38,11 +41,11 @@ func TestUseSpikeProfile(t *testing.T) {
 	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(1000), int64(1000)).
 		UseSpikeProfile(expectedSpikeProfile)
 
-	if sim.spikeProfile.Spikes[0].Percent != expectedSpikes[0].Percent || sim.spikeProfile.Spikes[0].Load != expectedSpikes[0].Load {
-		t.Error("Expected spike", expectedSpikes[0], "got", sim.spikeProfile.Spikes[0])
+	if sim.classes[0].SpikeProfile.Spikes[0].Percent != expectedSpikes[0].Percent || sim.classes[0].SpikeProfile.Spikes[0].Load != expectedSpikes[0].Load {
+		t.Error("Expected spike", expectedSpikes[0], "got", sim.classes[0].SpikeProfile.Spikes[0])
 	}
-	if sim.spikeProfile.Spikes[1].Percent != expectedSpikes[1].Percent || sim.spikeProfile.Spikes[1].Load != expectedSpikes[1].Load {
-		t.Error("Expected spike", expectedSpikes[1], "got", sim.spikeProfile.Spikes[1])
+	if sim.classes[0].SpikeProfile.Spikes[1].Percent != expectedSpikes[1].Percent || sim.classes[0].SpikeProfile.Spikes[1].Load != expectedSpikes[1].Load {
+		t.Error("Expected spike", expectedSpikes[1], "got", sim.classes[0].SpikeProfile.Spikes[1])
 	}
 }
 
@@ -134,17 +137,373 @@ func TestCreateBlocks(t *testing.T) {
 					numBlocks = 1
 
 					currentTxnTimestamp = drawFromPoisson(0.35)
-					pChan <- txn{currentTxnTimestamp, 0}
+					pChan <- newTestTxn(currentTxnTimestamp, 0)
 				} else {
 					numBlocks++
 				}
 
 			case _ = <-readyChan:
 				currentTxnTimestamp += drawFromPoisson(0.35)
-				pChan <- txn{currentTxnTimestamp, 0}
+				pChan <- newTestTxn(currentTxnTimestamp, 0)
 			}
 		}
 	}(sim, pendingTxnChan, readyChan, blockNumChan)
 
 	sim.createBlocks(pendingTxnChan, readyChan, blockNumChan)
 }
+
+func TestDrawTxnFitsBlock(t *testing.T) {
+	blockSize := 2000.0
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	classes := []TxnClass{
+		{Name: "tail", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_EXPONENTIAL, Mean: 1000.0}, FeeRate: ConstantDistribution(1.0)},
+		{Name: "batch", SpikeProfile: sp, Size: ConstantDistribution(250.0), FeeRate: ConstantDistribution(1.0),
+			Batching: &Batching{Interval: 3600.0, OutputSize: 100.0}},
+	}
+	sim := NewLoadSpikeSimulation(blockSize, int64(10), int64(1)).UseTxnClasses(classes)
+
+	// One in seven sizes of the tail class exceeds the block size
+	for i := 0; i < 1000; i++ {
+		if size := sim.drawTxn(0, 0.0, 1.0, 0).size; size > blockSize {
+			t.Fatal("Expected sizes to be redrawn to fit a block, got", size)
+		}
+	}
+
+	// Payments arrive every second, so the batch fills a block long before
+	// its interval ends and is broadcast with its last payment
+	batch := sim.drawTxn(1, 0.0, 1.0, 1)
+	if len(batch.payments) != 18 || batch.size != 1950.0 {
+		t.Error("Expected a batch of 18 payments and 1950 bytes, got", len(batch.payments), batch.size)
+	}
+	if batch.time != batch.payments[17] {
+		t.Error("Expected the full batch to be broadcast with its last payment, got", batch.time)
+	}
+}
+
+func TestSimulateOpenBatches(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.01}}}
+	plain := TxnClass{Name: "plain", SpikeProfile: sp, Size: ConstantDistribution(BITCOIN_TRANSACTION_SIZE), FeeRate: ConstantDistribution(1.0)}
+	// The batch is never broadcast within the iteration
+	batched := TxnClass{Name: "batched", SpikeProfile: sp, Size: ConstantDistribution(250.0), FeeRate: ConstantDistribution(1.0),
+		Batching: &Batching{Interval: 1e9, OutputSize: 34.0}}
+
+	// The open batch is either the next txn of the simulation or is still
+	// held back with another class
+	for _, classes := range [][]TxnClass{{batched}, {plain, batched}} {
+		sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(1)).
+			UseTxnClasses(classes).
+			UseSeed(5).
+			AddPaymentLogger("")
+		sim.simulate(false)
+
+		pl := sim.loggers[len(sim.loggers)-1].(*PaymentLogger)
+		plot := pl.cumulative.plots[len(classes)-1]
+		if plot.txnCount != 0 || plot.censoredCount == 0 {
+			t.Error("Expected the payments of the open batch to be censored, got", plot.txnCount, "confirmed and", plot.censoredCount, "censored")
+		}
+	}
+}
+
+func TestSimulateWithSeed(t *testing.T) {
+	sp := &SpikeProfile{
+		[]Spike{
+			Spike{0.0, 0.5},
+			Spike{0.5, 2.0},
+		},
+	}
+
+	first := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(20), int64(2)).
+		UseSpikeProfile(sp).
+		UseSeed(42).
+		Simulate()
+	second := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(20), int64(2)).
+		UseSpikeProfile(sp).
+		UseSeed(42).
+		Simulate()
+
+	if first.Iterations != 2 || len(first.Spikes) != 2 {
+		t.Fatal("Expected results for 2 iterations and 2 spikes, got", first.Iterations, "and", len(first.Spikes))
+	}
+	for i := range first.Spikes {
+		if first.Spikes[i] != second.Spikes[i] {
+			t.Error("Expected identical results for the same seed, got", first.Spikes[i], "and", second.Spikes[i])
+		}
+	}
+	if first.Spikes[0].Confirmed == 0 {
+		t.Error("Expected confirmed txns in spike 0")
+	}
+}
+
+func TestCancelWithContext(t *testing.T) {
+	sp := &SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	progress := []int64{}
+	results := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(5)).
+		UseSpikeProfile(sp).
+		UseContext(ctx).
+		UseProgress(func(iterations int64) {
+			progress = append(progress, iterations)
+			if iterations == 2 {
+				cancel()
+			}
+		}).
+		Simulate()
+
+	if results.Iterations != 2 || len(progress) != 2 || progress[1] != 2 {
+		t.Error("Expected the simulation to stop after 2 iterations, got", results.Iterations, progress)
+	}
+}
+
+/**
+ * Simulates a spike at half the capacity of the blocks with the features set
+ * by `configure`, keeping the logger state that `Simulate` resets.
+ */
+func simulateFeature(configure func(sim *LoadSpikeSimulation)) (*LoadSpikeSimulation, *BlockStatsLogger) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.5}}}
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(100), int64(5)).
+		UseSpikeProfile(&sp).
+		UseSeed(3)
+	configure(sim)
+
+	bsl := newBlockStatsLogger("", len(sim.classes))
+	sim.loggers = append(sim.loggers, bsl)
+	sim.simulate(false)
+	return sim, bsl
+}
+
+func TestSimulateTxnClasses(t *testing.T) {
+	// Together the classes exceed the capacity of the blocks
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.3}}}
+	classes := []TxnClass{
+		TxnClass{Name: "low", SpikeProfile: sp, Size: ConstantDistribution(BITCOIN_TRANSACTION_SIZE), FeeRate: ConstantDistribution(1.0)},
+		TxnClass{Name: "high", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_UNIFORM, Min: 500.0, Max: 1200.0}, FeeRate: ConstantDistribution(20.0)},
+	}
+
+	bsl := newBlockStatsLogger("", len(classes))
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(30), int64(3)).
+		UseTxnClasses(classes).
+		UseSeed(7)
+	sim.loggers = append(sim.loggers, bsl)
+	// Keep the logger state, which Simulate resets
+	sim.simulate(false)
+	results := sim.results
+
+	if len(results.Spikes) != 2 || results.Spikes[0].Class != "low" || results.Spikes[1].Class != "high" {
+		t.Fatal("Expected results for each class, got", results.Spikes)
+	}
+	low, high := results.Spikes[0], results.Spikes[1]
+	if low.Confirmed == 0 || high.Confirmed == 0 {
+		t.Fatal("Expected confirmed txns in both classes, got", low.Confirmed, "and", high.Confirmed)
+	}
+	if high.Median >= low.Median || high.Censored > low.Censored {
+		t.Error("Expected the higher fee class to confirm first, got medians", high.Median, "and", low.Median)
+	}
+
+	stats := bsl.heights[1]
+	if stats.totalTxns[0] == 0 || stats.totalTxns[1] == 0 {
+		t.Error("Expected blocks to include both classes, got", stats.totalTxns)
+	}
+	if stats.totalFees[1] <= 20.0*500.0*float64(stats.totalTxns[1])-1e-6 {
+		t.Error("Expected high class fees to use the sampled sizes, got", stats.totalFees[1])
+	}
+}
+
+func TestFillBatch(t *testing.T) {
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(1))
+	batch := func(b *Batching, tps float64) txn {
+		batched := txn{time: 100.0, size: 250.0}
+		sim.fillBatch(&batched, b, tps)
+		return batched
+	}
+
+	// No payment arrives before the deadline
+	lone := batch(&Batching{Interval: 600.0, OutputSize: 34.0}, 1e-12)
+	if len(lone.payments) != 1 || lone.time != 700.0 || lone.size != 250.0 {
+		t.Error("Expected a single payment broadcast at the deadline, got", lone.payments, lone.time, lone.size)
+	}
+
+	// Payments arrive immediately until the batch holds `MaxPayments`
+	capped := batch(&Batching{Interval: 600.0, MaxPayments: 3, OutputSize: 34.0}, 1e12)
+	if len(capped.payments) != 3 || capped.time != capped.payments[2] || capped.size != 250.0+2*34.0 {
+		t.Error("Expected a full batch of 3 payments broadcast at the last one, got", capped.payments, capped.time, capped.size)
+	}
+	if capped.time >= 700.0 {
+		t.Error("Expected a full batch before the deadline, got", capped.time)
+	}
+
+	// Another output would not fit in a block of 318 bytes
+	sim.blockSize = 250.0 + 2*34.0
+	bounded := batch(&Batching{Interval: 600.0, OutputSize: 34.0}, 1e12)
+	if len(bounded.payments) != 3 || bounded.size != sim.blockSize {
+		t.Error("Expected the batch to fill the block, got", bounded.payments, bounded.size)
+	}
+}
+
+func TestBidding(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.5}}}
+	classes := []TxnClass{
+		TxnClass{Name: "fixed", SpikeProfile: sp, Size: ConstantDistribution(BITCOIN_TRANSACTION_SIZE), FeeRate: ConstantDistribution(5.0)},
+		TxnClass{Name: "bidding", SpikeProfile: sp, Size: ConstantDistribution(BITCOIN_TRANSACTION_SIZE), FeeRate: ConstantDistribution(5.0),
+			Bidding: &Bidding{TargetBlocks: 2.0, Premium: 0.1, MaxFeeRate: ConstantDistribution(20.0)}},
+	}
+	sim := NewLoadSpikeSimulation(BITCOIN_TRANSACTION_SIZE, int64(10), int64(1)).
+		UseTxnClasses(classes)
+
+	pool := newMempool()
+	for i, next := range newTestTxns(4) {
+		next.feeRate = float64(10 * (i + 1))
+		pool.add(next)
+	}
+
+	// Bidding users observe the fee rate two blocks deep
+	market := make([]float64, len(classes))
+	sim.observeMarket(pool, market, BITCOIN_TRANSACTION_SIZE)
+	if market[0] != 0.0 || market[1] != 20.0 {
+		t.Fatal("Expected a market fee rate of 20 only for the bidding class, got", market)
+	}
+
+	fixed := txn{class: 0, feeRate: 5.0}
+	if !sim.bid(&fixed, market) || fixed.feeRate != 5.0 {
+		t.Error("Expected the fixed class to keep its fee rate, got", fixed.feeRate)
+	}
+	bidder := txn{class: 1, feeRate: 5.0, maxFeeRate: 30.0}
+	if !sim.bid(&bidder, market) || bidder.feeRate != 22.0 {
+		t.Error("Expected a bid of the market fee rate plus the premium, got", bidder.feeRate)
+	}
+	priced := txn{class: 1, feeRate: 5.0, maxFeeRate: 20.0}
+	if sim.bid(&priced, market) {
+		t.Error("Expected a user whose maximum is below the bid to abstain, got", priced.feeRate)
+	}
+}
+
+func TestMineBlockMiners(t *testing.T) {
+	sim := NewLoadSpikeSimulation(4*BITCOIN_TRANSACTION_SIZE, int64(10), int64(1)).
+		UseMiners([]Miner{Miner{Name: "capped", Hashrate: 1.0, Policy: TEMPLATE_FIFO, SoftLimit: 2 * BITCOIN_TRANSACTION_SIZE}})
+	pool := newMempool()
+	for i, next := range newTestTxns(4) {
+		// Later txns pay more
+		next.feeRate = float64(i + 1)
+		pool.add(next)
+	}
+
+	// The only miner takes the oldest txns up to its soft limit
+	b := sim.mineBlock(pool, 0, 600.0, 600.0)
+	if b.miner != 0 || b.numTxns != 2 || b.txns[0].time != 0.0 || b.txns[1].time != 1.0 {
+		t.Error("Expected the 2 oldest txns, got", b.txns)
+	}
+	if b.maxSize != 4*BITCOIN_TRANSACTION_SIZE || b.size != 2*BITCOIN_TRANSACTION_SIZE {
+		t.Error("Expected a block at the soft limit below the maximum size, got", b.size, "of", b.maxSize)
+	}
+}
+
+func TestMineBlockSPVMining(t *testing.T) {
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(1)).
+		UseSPVMining(60.0, 1.0)
+	pool := newMempool()
+	for _, tx := range newTestTxns(4) {
+		pool.add(tx)
+	}
+
+	// A block within the window is mined before the previous one validated
+	spv := sim.mineBlock(pool, 1, 630.0, 30.0)
+	if !spv.spv || spv.numTxns != 0 || spv.size != 0.0 || pool.Len() != 4 {
+		t.Error("Expected an empty SPV block, got", spv.numTxns, "txns")
+	}
+
+	validated := sim.mineBlock(pool, 2, 750.0, 120.0)
+	if validated.spv || validated.numTxns != 4 || pool.Len() != 0 {
+		t.Error("Expected a validated block with every txn, got", validated.numTxns, "txns")
+	}
+}
+
+func TestSimulateSPVMining(t *testing.T) {
+	sim, bsl := simulateFeature(func(sim *LoadSpikeSimulation) {
+		sim.UseSPVMining(60.0, 1.0)
+	})
+
+	numSPV := int64(0)
+	for _, stats := range bsl.heights {
+		numSPV += stats.numSPV
+	}
+	if sim.spvBlocks == 0 || numSPV != sim.spvBlocks {
+		t.Error("Expected every SPV block in the block statistics, got", numSPV, "of", sim.spvBlocks)
+	}
+}
+
+func TestSimulatePropagation(t *testing.T) {
+	sim, bsl := simulateFeature(func(sim *LoadSpikeSimulation) {
+		sim.UsePropagation(60.0, 300.0)
+	})
+
+	if sim.orphaned == 0 || len(sim.reorgs) == 0 {
+		t.Fatal("Expected orphaned blocks and reorgs, got", sim.orphaned, sim.reorgs)
+	}
+	// The winning chain has a block at every height of every iteration
+	for i, stats := range bsl.heights {
+		if stats.numBlocks != sim.numIterations {
+			t.Error("Expected", sim.numIterations, "blocks at height", i, ", got", stats.numBlocks)
+		}
+	}
+}
+
+func TestMineBlockSizePolicy(t *testing.T) {
+	sim := NewLoadSpikeSimulation(10*BITCOIN_TRANSACTION_SIZE, int64(20), int64(1)).
+		UseBlockSizePolicy(NewScheduledBlockSize(10*BITCOIN_TRANSACTION_SIZE, 10, 30*BITCOIN_TRANSACTION_SIZE))
+	pool := newMempool()
+	for _, tx := range newTestTxns(30) {
+		pool.add(tx)
+	}
+
+	// Halfway through the schedule the limit is 20 txns
+	b := sim.mineBlock(pool, 10, 600.0, 600.0)
+	if b.maxSize != 20*BITCOIN_TRANSACTION_SIZE || b.numTxns != 20 || pool.Len() != 10 {
+		t.Fatal("Expected a block of 20 txns at the scheduled size, got", b.numTxns, "of", b.maxSize)
+	}
+
+	// The fill ratio is relative to the policy's size rather than the
+	// initial block size
+	bsl := newBlockStatsLogger("", 1)
+	bsl.LogBlock(b, pool)
+	if fill := bsl.heights[10].totalFill; fill != 1.0 {
+		t.Error("Expected a fill ratio of 1, got", fill)
+	}
+}
+
+func TestMineBlockVotedBelowLargestTxn(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	large := TxnClass{Name: "large", SpikeProfile: sp, Size: ConstantDistribution(5 * BITCOIN_TRANSACTION_SIZE), FeeRate: ConstantDistribution(1.0)}
+	policy := NewVotingBlockSize(10*BITCOIN_TRANSACTION_SIZE, 1, 1.0, 0.0, []float64{BITCOIN_TRANSACTION_SIZE})
+	sim := NewLoadSpikeSimulation(10*BITCOIN_TRANSACTION_SIZE, int64(10), int64(1)).
+		UseTxnClasses([]TxnClass{large}).
+		UseBlockSizePolicy(policy)
+
+	// The only miner votes the size down to a single small txn
+	policy.ObserveBlock(block{miner: 0})
+	if size := policy.MaxBlockSize(1); size != BITCOIN_TRANSACTION_SIZE {
+		t.Fatal("Expected the vote to be adopted, got", size)
+	}
+
+	pool := newMempool()
+	pool.add(txn{size: 5 * BITCOIN_TRANSACTION_SIZE, feeRate: 1.0})
+	b := sim.mineBlock(pool, 1, 600.0, 600.0)
+	if b.maxSize != 5*BITCOIN_TRANSACTION_SIZE || b.numTxns != 1 || pool.Len() != 0 {
+		t.Error("Expected the maximum size to fit the largest txn of the class, got", b.maxSize, "and", b.numTxns, "txns")
+	}
+}
+
+func TestMineBlockWithPenalty(t *testing.T) {
+	sim := NewLoadSpikeSimulation(2*BITCOIN_TRANSACTION_SIZE, int64(10), int64(1)).
+		UsePenalty(PENALTY_LINEAR, 1e6, 2.0)
+	pool := newMempool()
+	for _, tx := range newTestTxns(5) {
+		tx.feeRate = 1000.0
+		pool.add(tx)
+	}
+
+	// Each txn beyond the limit costs half the reward, less than its fee
+	b := sim.mineBlock(pool, 0, 600.0, 600.0)
+	if b.numTxns != 4 || b.size != 4*BITCOIN_TRANSACTION_SIZE || b.penalty != 1e6 {
+		t.Error("Expected a block of twice the maximum size paying the reward, got", b.numTxns, b.penalty)
+	}
+}
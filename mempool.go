@@ -0,0 +1,312 @@
+package bitcoin_load_spike
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Consecutive `txn`s that may not fit before a block is considered full
+const MAX_CONSECUTIVE_FAILURES = 1000
+
+/**
+ * `mempool`
+ *
+ * Holds the `txn`s that have arrived but have not yet been included in a
+ * block, as a heap ordered by fee rate and then by arrival.  Also tracks the
+ * number and total size in bytes of the pending `txn`s of each class.
+ */
+type mempool struct {
+	txns       []txn
+	size       float64
+	classLens  []int
+	classSizes []float64
+}
+
+/**
+ * Initializes a new `mempool`
+ *
+ * @return - An empty `mempool`
+ */
+func newMempool() *mempool {
+	return &mempool{
+		txns: []txn{},
+		size: 0.0,
+	}
+}
+
+/**
+ * @return - The number of pending `txn`s
+ */
+func (mp *mempool) Len() int {
+	return len(mp.txns)
+}
+
+/**
+ * @return - The total size of the pending `txn`s in bytes
+ */
+func (mp *mempool) Size() float64 {
+	return mp.size
+}
+
+/**
+ * @param class - The index of a `TxnClass`
+ *
+ * @return - The number of pending `txn`s of `class`
+ */
+func (mp *mempool) ClassLen(class int) int {
+	if class >= len(mp.classLens) {
+		return 0
+	}
+	return mp.classLens[class]
+}
+
+/**
+ * @param class - The index of a `TxnClass`
+ *
+ * @return - The total size of the pending `txn`s of `class` in bytes
+ */
+func (mp *mempool) ClassSize(class int) float64 {
+	if class >= len(mp.classSizes) {
+		return 0.0
+	}
+	return mp.classSizes[class]
+}
+
+/**
+ * Adds a newly arrived `txn` to the `mempool`
+ *
+ * @param t - The arriving `txn`
+ */
+func (mp *mempool) add(t txn) {
+	heap.Push((*txnHeap)(&mp.txns), t)
+	mp.account(t, 1)
+}
+
+/**
+ * @return - A copy of the `mempool` that can be mined independently
+ */
+func (mp *mempool) clone() *mempool {
+	return &mempool{
+		txns:       append([]txn{}, mp.txns...),
+		size:       mp.size,
+		classLens:  append([]int{}, mp.classLens...),
+		classSizes: append([]float64{}, mp.classSizes...),
+	}
+}
+
+/**
+ * Returns the pending `txn`s that arrived at or after `timestamp`.  The
+ * returned `mempool` shares its `txn`s with the original if they all
+ * arrived after `timestamp`.
+ *
+ * @param timestamp - The earliest arrival time to include
+ *
+ * @return - The `mempool` of recent `txn`s
+ */
+func (mp *mempool) arrivedSince(timestamp float64) *mempool {
+	recent := newMempool()
+	for _, t := range mp.txns {
+		if t.time >= timestamp {
+			recent.txns = append(recent.txns, t)
+			recent.account(t, 1)
+		}
+	}
+	if len(recent.txns) == len(mp.txns) {
+		return mp
+	}
+
+	// Filtering a heap does not keep the heap order in general
+	heap.Init((*txnHeap)(&recent.txns))
+	return recent
+}
+
+/**
+ * Removes the `txn`s with the highest fee rates that fit within `maxSize`
+ * bytes, the oldest first among equal fee rates.  `txn`s too large for the
+ * remaining space are skipped, until `MAX_CONSECUTIVE_FAILURES` are skipped
+ * in a row.
+ *
+ * @param maxSize - The maximum size of the block in bytes
+ *
+ * @return - The `txn`s included in the block and their total size
+ */
+func (mp *mempool) fillBlock(maxSize float64) ([]txn, float64) {
+	return mp.fillBlockWhile(func(size float64, t txn) bool {
+		return size+t.size <= maxSize
+	})
+}
+
+/**
+ * Removes the `txn`s with the highest fee rates that fit within `maxSize`
+ * bytes, and then those whose fees outweigh the increase of the `Penalty`
+ * of a larger block.
+ *
+ * @param maxSize - The maximum size of the block in bytes without a penalty
+ * @param p - The `Penalty` of larger blocks
+ *
+ * @return - The `txn`s included in the block and their total size
+ */
+func (mp *mempool) fillBlockWithPenalty(maxSize float64, p *Penalty) ([]txn, float64) {
+	return mp.fillBlockWhile(func(size float64, t txn) bool {
+		return size+t.size <= maxSize || p.worth(size, t, maxSize)
+	})
+}
+
+/**
+ * Removes the `txn`s with the highest fee rates, the oldest first among
+ * equal fee rates, that `fits` the block.  `txn`s that do not fit are
+ * skipped, until `MAX_CONSECUTIVE_FAILURES` are skipped in a row.
+ *
+ * @param fits - Whether a `txn` fits a block of the given size
+ *
+ * @return - The `txn`s included in the block and their total size
+ */
+func (mp *mempool) fillBlockWhile(fits func(size float64, t txn) bool) (included []txn, size float64) {
+	h := (*txnHeap)(&mp.txns)
+	skipped := []txn{}
+	for failures := 0; len(mp.txns) > 0 && failures < MAX_CONSECUTIVE_FAILURES; {
+		t := heap.Pop(h).(txn)
+		if !fits(size, t) {
+			skipped = append(skipped, t)
+			failures++
+			continue
+		}
+
+		included = append(included, t)
+		size += t.size
+		mp.account(t, -1)
+		failures = 0
+	}
+	// Skipped txns wait for the next block
+	for _, s := range skipped {
+		heap.Push(h, s)
+	}
+	mp.clearRoundoff()
+
+	return
+}
+
+/**
+ * Removes the oldest `txn`s that fit within `maxSize` bytes, regardless of
+ * their fee rates.  `txn`s too large for the remaining space are skipped, as
+ * in `fillBlock`.
+ *
+ * @param maxSize - The maximum size of the block in bytes
+ *
+ * @return - The `txn`s included in the block and their total size
+ */
+func (mp *mempool) fillBlockOldest(maxSize float64) (included []txn, size float64) {
+	ordered := append([]txn{}, mp.txns...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].time < ordered[j].time
+	})
+
+	remaining := []txn{}
+	failures := 0
+	for _, t := range ordered {
+		if failures >= MAX_CONSECUTIVE_FAILURES || size+t.size > maxSize {
+			remaining = append(remaining, t)
+			failures++
+			continue
+		}
+
+		included = append(included, t)
+		size += t.size
+		mp.account(t, -1)
+		failures = 0
+	}
+	mp.txns = remaining
+	heap.Init((*txnHeap)(&mp.txns))
+	mp.clearRoundoff()
+
+	return
+}
+
+/**
+ * Zeroes the sizes of an empty `mempool`, which would otherwise accumulate
+ * floating point roundoff.
+ */
+func (mp *mempool) clearRoundoff() {
+	if len(mp.txns) == 0 {
+		mp.size = 0.0
+		for i := range mp.classSizes {
+			mp.classSizes[i] = 0.0
+		}
+	}
+}
+
+/**
+ * Returns the fee rate of the pending `txn` at `depth` bytes, counting from
+ * the `txn` that would be included first.
+ *
+ * @param depth - The number of bytes of `txn`s with priority
+ *
+ * @return - The fee rate in satoshis per byte, 0 if the `mempool` holds less
+ *           than `depth` bytes
+ */
+func (mp *mempool) feeRateAtDepth(depth float64) float64 {
+	if mp.size <= depth {
+		return 0.0
+	}
+
+	ordered := append(txnHeap{}, mp.txns...)
+	sort.Sort(ordered)
+	size := 0.0
+	for _, t := range ordered {
+		size += t.size
+		if size > depth {
+			return t.feeRate
+		}
+	}
+	return 0.0
+}
+
+/**
+ * Adds or removes `t` from the totals of the `mempool` and its class.
+ *
+ * @param t - The added or removed `txn`
+ * @param sign - 1 when adding `t`, -1 when removing it
+ */
+func (mp *mempool) account(t txn, sign int) {
+	if t.class >= len(mp.classLens) {
+		mp.classLens = append(mp.classLens, make([]int, t.class-len(mp.classLens)+1)...)
+		mp.classSizes = append(mp.classSizes, make([]float64, t.class-len(mp.classSizes)+1)...)
+	}
+
+	mp.size += float64(sign) * t.size
+	mp.classLens[t.class] += sign
+	mp.classSizes[t.class] += float64(sign) * t.size
+}
+
+/**
+ * `txnHeap`
+ *
+ * Orders `txn`s by decreasing fee rate, then by arrival, for `container/heap`.
+ */
+type txnHeap []txn
+
+func (h txnHeap) Len() int {
+	return len(h)
+}
+
+func (h txnHeap) Less(i, j int) bool {
+	if h[i].feeRate != h[j].feeRate {
+		return h[i].feeRate > h[j].feeRate
+	}
+	return h[i].time < h[j].time
+}
+
+func (h txnHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *txnHeap) Push(x interface{}) {
+	*h = append(*h, x.(txn))
+}
+
+func (h *txnHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,129 @@
+package bitcoin_load_spike
+
+import "testing"
+
+/**
+ * Builds a `txn` of the default class.
+ */
+func newTestTxn(time float64, index int) txn {
+	return txn{
+		time:    time,
+		index:   index,
+		size:    BITCOIN_TRANSACTION_SIZE,
+		feeRate: DEFAULT_FEE_RATE,
+	}
+}
+
+/**
+ * Builds `n` `txn`s of the default class.
+ */
+func newTestTxns(n int) (txns []txn) {
+	for i := 0; i < n; i++ {
+		txns = append(txns, newTestTxn(float64(i), 0))
+	}
+	return
+}
+
+func TestMempoolFillBlock(t *testing.T) {
+	pool := newMempool()
+	for i := float64(0); i < 5; i++ {
+		pool.add(newTestTxn(i, 0))
+	}
+
+	if pool.Len() != 5 {
+		t.Error("Expected 5 pending txns, got", pool.Len())
+	}
+	if pool.Size() != 5*BITCOIN_TRANSACTION_SIZE {
+		t.Error("Expected pending size", 5*BITCOIN_TRANSACTION_SIZE, ", got", pool.Size())
+	}
+
+	// Only room for 3 txns, oldest first
+	included, size := pool.fillBlock(3.5 * BITCOIN_TRANSACTION_SIZE)
+	if len(included) != 3 || included[0].time != 0.0 || included[2].time != 2.0 {
+		t.Error("Expected the 3 oldest txns to be included, got", included)
+	}
+	if size != 3*BITCOIN_TRANSACTION_SIZE {
+		t.Error("Expected block size", 3*BITCOIN_TRANSACTION_SIZE, ", got", size)
+	}
+	if pool.Len() != 2 {
+		t.Error("Expected 2 pending txns, got", pool.Len())
+	}
+
+	included, _ = pool.fillBlock(DEFAULT_BLOCK_SIZE)
+	if len(included) != 2 || pool.Len() != 0 || pool.Size() != 0.0 {
+		t.Error("Expected mempool to be emptied, got", pool.Len(), "txns")
+	}
+}
+
+func TestMempoolFeePriority(t *testing.T) {
+	pool := newMempool()
+	pool.add(txn{time: 0.0, class: 0, size: 400.0, feeRate: 1.0})
+	pool.add(txn{time: 1.0, class: 1, size: 600.0, feeRate: 10.0})
+	pool.add(txn{time: 2.0, class: 1, size: 200.0, feeRate: 5.0})
+	pool.add(txn{time: 3.0, class: 0, size: 100.0, feeRate: 1.0})
+
+	if pool.ClassLen(1) != 2 || pool.ClassSize(1) != 800.0 || pool.ClassLen(2) != 0 {
+		t.Error("Expected 2 txns of 800 bytes in class 1, got", pool.ClassLen(1), pool.ClassSize(1))
+	}
+
+	// The 400 byte txn does not fit, the smaller and newer one does
+	included, size := pool.fillBlock(1000.0)
+	if len(included) != 3 || included[0].feeRate != 10.0 || included[1].feeRate != 5.0 || included[2].time != 3.0 {
+		t.Error("Expected txns in order of fee rate, got", included)
+	}
+	if size != 900.0 || pool.Len() != 1 || pool.Size() != 400.0 {
+		t.Error("Expected 900 bytes included and 400 pending, got", size, pool.Size())
+	}
+	if pool.ClassLen(0) != 1 || pool.ClassSize(1) != 0.0 {
+		t.Error("Expected the class totals to be updated, got", pool.ClassLen(0), pool.ClassSize(1))
+	}
+}
+
+func TestMempoolFeeRateAtDepth(t *testing.T) {
+	pool := newMempool()
+	for i, next := range newTestTxns(4) {
+		next.feeRate = float64(10 * (i + 1))
+		pool.add(next)
+	}
+
+	expected := map[float64]float64{
+		0.0:                            40.0,
+		BITCOIN_TRANSACTION_SIZE:       30.0,
+		2.5 * BITCOIN_TRANSACTION_SIZE: 20.0,
+		4.0 * BITCOIN_TRANSACTION_SIZE: 0.0,
+	}
+	for depth, feeRate := range expected {
+		if rate := pool.feeRateAtDepth(depth); rate != feeRate {
+			t.Error("Expected fee rate", feeRate, "at depth", depth, ", got", rate)
+		}
+	}
+	if pool.Len() != 4 {
+		t.Error("Expected the mempool to be unchanged, got", pool.Len(), "txns")
+	}
+}
+
+func TestMempoolArrivedSince(t *testing.T) {
+	pool := newMempool()
+	for i, feeRate := range []float64{30.0, 10.0, 20.0} {
+		next := newTestTxn(float64(i), 0)
+		next.feeRate = feeRate
+		pool.add(next)
+	}
+
+	if recent := pool.arrivedSince(0.0); recent != pool {
+		t.Error("Expected the mempool itself when every txn arrived since")
+	}
+
+	// Dropping the root leaves the remaining txns out of heap order
+	recent := pool.arrivedSince(1.0)
+	if recent.Len() != 2 || recent.Size() != 2*BITCOIN_TRANSACTION_SIZE {
+		t.Error("Expected 2 recent txns, got", recent.Len())
+	}
+	included, _ := recent.fillBlock(BITCOIN_TRANSACTION_SIZE)
+	if len(included) != 1 || included[0].feeRate != 20.0 {
+		t.Error("Expected the highest recent fee rate first, got", included)
+	}
+	if pool.Len() != 3 {
+		t.Error("Expected the mempool to be unchanged, got", pool.Len(), "txns")
+	}
+}
@@ -0,0 +1,311 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upper bounds in seconds of the confirmation time histogram buckets
+var METRICS_CONFIRMATION_BUCKETS = []float64{
+	60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400, 28800, 86400, 604800,
+}
+
+// Content types of the Prometheus text and OpenMetrics expositions
+const PROMETHEUS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+const OPENMETRICS_CONTENT_TYPE = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+/**
+ * `MetricsLogger`
+ *
+ * Exposes live metrics of a running simulation to Prometheus.  Records the
+ * completed iterations, the simulated `txn`s and their rate, the current
+ * `mempool` backlog and a histogram of the confirmation times of each spike
+ * of each `TxnClass`.
+ * The simulation updates the metrics while `ServeHTTP` reads them, so every
+ * access holds `mu`.
+ *
+ * Counters and histograms accumulate across runs, as Prometheus expects, so
+ * `Reset` keeps them.  The logger writes no output files.
+ */
+type MetricsLogger struct {
+	mu sync.Mutex
+
+	targetIterations int64
+	spikes           []classSpike
+	iterations       int64
+	arrivals         int64
+	confirmed        int64
+	started          time.Time
+	backlogTxns      int
+	backlogBytes     float64
+	histograms       []*confirmationHistogram
+}
+
+/**
+ * Cumulative counts of confirmation times below each of the
+ * `METRICS_CONFIRMATION_BUCKETS`, the final count is unbounded.
+ */
+type confirmationHistogram struct {
+	counts []int64
+	sum    float64
+}
+
+/**
+ * Initializes a new `MetricsLogger`.
+ *
+ * @return - An empty `MetricsLogger`
+ */
+func NewMetricsLogger() *MetricsLogger {
+	return &MetricsLogger{}
+}
+
+/**
+ * The `MetricsLogger` writes no files.
+ */
+func (ml *MetricsLogger) FilePrefix() string {
+	return ""
+}
+
+/**
+ * The `MetricsLogger` writes no files.
+ */
+func (ml *MetricsLogger) FileExtension() string {
+	return ""
+}
+
+/**
+ * Records the confirmation time of `t` in the histogram of its spike.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (ml *MetricsLogger) Log(blockTimestamp float64, t txn) {
+	confirmationTime := blockTimestamp - t.time
+
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	for len(ml.histograms) <= t.index {
+		ml.histograms = append(ml.histograms, &confirmationHistogram{
+			counts: make([]int64, len(METRICS_CONFIRMATION_BUCKETS)+1),
+		})
+	}
+	h := ml.histograms[t.index]
+	for i, bound := range METRICS_CONFIRMATION_BUCKETS {
+		if confirmationTime <= bound {
+			h.counts[i]++
+		}
+	}
+	h.counts[len(METRICS_CONFIRMATION_BUCKETS)]++
+	h.sum += confirmationTime
+	ml.confirmed++
+}
+
+/**
+ * Counts the arriving `txn` and records the backlog.
+ *
+ * @param t - The arriving `txn`
+ * @param pool - The `mempool` after `t` was added
+ */
+func (ml *MetricsLogger) LogArrival(t txn, pool *mempool) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	if ml.started.IsZero() {
+		ml.started = time.Now()
+	}
+	ml.arrivals++
+	ml.backlogTxns = pool.Len()
+	ml.backlogBytes = pool.Size()
+}
+
+/**
+ * Records the backlog after a block is mined.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (ml *MetricsLogger) LogBlock(b block, pool *mempool) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	ml.backlogTxns = pool.Len()
+	ml.backlogBytes = pool.Size()
+}
+
+/**
+ * Counts the completed iteration.  The next iteration starts with an empty
+ * `mempool`.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (ml *MetricsLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	ml.iterations++
+	ml.backlogTxns = 0
+	ml.backlogBytes = 0.0
+}
+
+/**
+ * The metrics are scraped rather than written to files.
+ *
+ * @return - No outputs
+ */
+func (ml *MetricsLogger) Outputs() []string {
+	return nil
+}
+
+/**
+ * Keeps the accumulated metrics, Prometheus counters never decrease.
+ */
+func (ml *MetricsLogger) Reset() {}
+
+/**
+ * Sets the number of iterations the simulation is expected to perform and
+ * the spikes used to label the histograms.
+ *
+ * @param iterations - The maximum number of iterations of the simulation
+ * @param spikes - The spikes of every `TxnClass` of the simulation
+ */
+func (ml *MetricsLogger) configure(iterations int64, spikes []classSpike) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	ml.targetIterations = iterations
+	ml.spikes = spikes
+}
+
+/**
+ * Serves the metrics in the OpenMetrics format if the scraper accepts it,
+ * otherwise in the Prometheus text format.
+ */
+func (ml *MetricsLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", OPENMETRICS_CONTENT_TYPE)
+	} else {
+		w.Header().Set("Content-Type", PROMETHEUS_CONTENT_TYPE)
+	}
+	ml.WriteMetrics(w, openMetrics)
+}
+
+/**
+ * Writes every metric in the Prometheus text or OpenMetrics format.
+ *
+ * @param w - The destination of the exposition
+ * @param openMetrics - Whether to use the OpenMetrics format
+ *
+ * @return - Any error writing to `w`
+ */
+func (ml *MetricsLogger) WriteMetrics(w io.Writer, openMetrics bool) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	txnsPerSecond := 0.0
+	if !ml.started.IsZero() {
+		txnsPerSecond = float64(ml.arrivals) / time.Since(ml.started).Seconds()
+	}
+
+	e := &exposition{w: w, openMetrics: openMetrics}
+	e.counter("bls_iterations", "Completed simulation iterations.", float64(ml.iterations))
+	e.gauge("bls_target_iterations", "Maximum number of iterations of the simulation.", float64(ml.targetIterations))
+	e.counter("bls_txns_simulated", "Simulated txn arrivals.", float64(ml.arrivals))
+	e.counter("bls_txns_confirmed", "Confirmed txns recorded after the warm-up period.", float64(ml.confirmed))
+	e.gauge("bls_txns_per_second", "Simulated txn arrivals per second of wall clock time.", txnsPerSecond)
+	e.gauge("bls_backlog_txns", "Unconfirmed txns in the mempool of the current iteration.", float64(ml.backlogTxns))
+	e.gauge("bls_backlog_bytes", "Size in bytes of the unconfirmed txns in the mempool of the current iteration.", ml.backlogBytes)
+
+	e.family("bls_confirmation_seconds", "histogram", "Confirmation times of the txns of each spike.")
+	for i, h := range ml.histograms {
+		spike := fmt.Sprintf(`spike="%d"`, i)
+		if i < len(ml.spikes) && ml.spikes[i].name != "" {
+			spike = fmt.Sprintf(`class="%s",spike="%d"`, ml.spikes[i].name, ml.spikes[i].spike)
+		}
+		for j, bound := range METRICS_CONFIRMATION_BUCKETS {
+			e.sample("bls_confirmation_seconds_bucket", spike+`,le="`+formatMetric(bound)+`"`, float64(h.counts[j]))
+		}
+		count := float64(h.counts[len(METRICS_CONFIRMATION_BUCKETS)])
+		e.sample("bls_confirmation_seconds_bucket", spike+`,le="+Inf"`, count)
+		e.sample("bls_confirmation_seconds_sum", spike, h.sum)
+		e.sample("bls_confirmation_seconds_count", spike, count)
+	}
+
+	if openMetrics {
+		e.line("# EOF")
+	}
+	return e.err
+}
+
+/**
+ * `exposition`
+ *
+ * Writes metric families in the text format, keeping the first error.
+ */
+type exposition struct {
+	w           io.Writer
+	openMetrics bool
+	err         error
+}
+
+/**
+ * Writes a counter.  Counter samples end in `_total`, which OpenMetrics
+ * omits from the family name.
+ */
+func (e *exposition) counter(name, help string, value float64) {
+	family := name + "_total"
+	if e.openMetrics {
+		family = name
+	}
+	e.family(family, "counter", help)
+	e.sample(name+"_total", "", value)
+}
+
+func (e *exposition) gauge(name, help string, value float64) {
+	e.family(name, "gauge", help)
+	e.sample(name, "", value)
+}
+
+func (e *exposition) family(name, kind, help string) {
+	e.line("# HELP " + name + " " + help)
+	e.line("# TYPE " + name + " " + kind)
+}
+
+func (e *exposition) sample(name, labels string, value float64) {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	e.line(name + " " + formatMetric(value))
+}
+
+func (e *exposition) line(s string) {
+	if e.err == nil {
+		_, e.err = io.WriteString(e.w, s+"\n")
+	}
+}
+
+/**
+ * Formats a sample value as the text formats expect.
+ */
+func formatMetric(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		// Counts read better without an exponent
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,93 @@
+package bitcoin_load_spike
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsLoggerExposition(t *testing.T) {
+	ml := NewMetricsLogger()
+	ml.configure(4, nil)
+	pool := newMempool()
+
+	pool.add(newTestTxn(10.0, 0))
+	ml.LogArrival(newTestTxn(10.0, 0), pool)
+	pool.add(newTestTxn(20.0, 1))
+	ml.LogArrival(newTestTxn(20.0, 1), pool)
+	ml.Log(100.0, newTestTxn(10.0, 0))
+	ml.Log(5000.0, newTestTxn(20.0, 1))
+	ml.EndIteration(5000.0, newMempool())
+
+	var b bytes.Buffer
+	if err := ml.WriteMetrics(&b, false); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+
+	expectedLines := []string{
+		"# TYPE bls_iterations_total counter",
+		"bls_iterations_total 1",
+		"bls_target_iterations 4",
+		"bls_txns_simulated_total 2",
+		"bls_txns_confirmed_total 2",
+		"bls_backlog_txns 0",
+		"# TYPE bls_confirmation_seconds histogram",
+		`bls_confirmation_seconds_bucket{spike="0",le="60"} 0`,
+		`bls_confirmation_seconds_bucket{spike="0",le="120"} 1`,
+		`bls_confirmation_seconds_bucket{spike="0",le="+Inf"} 1`,
+		`bls_confirmation_seconds_sum{spike="0"} 90`,
+		`bls_confirmation_seconds_bucket{spike="1",le="3600"} 0`,
+		`bls_confirmation_seconds_bucket{spike="1",le="7200"} 1`,
+		`bls_confirmation_seconds_count{spike="1"} 1`,
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line+"\n") {
+			t.Error("Expected line '", line, "' in output '", output, "'")
+		}
+	}
+	if strings.Contains(output, "# EOF") {
+		t.Error("Expected no EOF marker in the Prometheus format")
+	}
+}
+
+func TestMetricsLoggerOpenMetrics(t *testing.T) {
+	ml := NewMetricsLogger()
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	ml.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Type") != OPENMETRICS_CONTENT_TYPE {
+		t.Error("Expected content type", OPENMETRICS_CONTENT_TYPE, ", got", w.Header().Get("Content-Type"))
+	}
+	output := w.Body.String()
+	if !strings.Contains(output, "# TYPE bls_iterations counter\nbls_iterations_total 0\n") {
+		t.Error("Expected counter family without the _total suffix, got", output)
+	}
+	if !strings.HasSuffix(output, "# EOF\n") {
+		t.Error("Expected output to end with the EOF marker, got", output)
+	}
+}
+
+func TestMetricsLoggerSimulation(t *testing.T) {
+	ml := NewMetricsLogger()
+	NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, 20, 3).
+		UseSpikeProfile(&SpikeProfile{[]Spike{Spike{0.0, 0.5}}}).
+		UseSeed(1).
+		AddMetricsLogger(ml).
+		Simulate()
+
+	if ml.iterations != 3 {
+		t.Error("Expected 3 iterations, got", ml.iterations)
+	}
+	if ml.arrivals == 0 || ml.confirmed == 0 || ml.confirmed > ml.arrivals {
+		t.Error("Expected confirmed txns among the arrivals, got", ml.confirmed, "of", ml.arrivals)
+	}
+	if len(ml.histograms) != 1 || ml.histograms[0].counts[len(METRICS_CONFIRMATION_BUCKETS)] != ml.confirmed {
+		t.Error("Expected every confirmed txn in the histogram")
+	}
+}
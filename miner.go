@@ -0,0 +1,205 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+)
+
+// Block template policies of a `Miner`
+const (
+	TEMPLATE_FEE   = "fee"
+	TEMPLATE_FIFO  = "fifo"
+	TEMPLATE_EMPTY = "empty"
+)
+
+/**
+ * `Miner`
+ *
+ * A miner, or pool, finding a `Hashrate` share of the blocks.  Shares are
+ * relative to the total hashrate of the simulation's miners, so they need
+ * not sum to 1.  Blocks are found at the network's `BITCOIN_BLOCK_RATE`, and
+ * each block is found by a miner with probability equal to its share, so
+ * every miner finds blocks as an independent Poisson process.
+ *
+ * The `Policy` decides which pending `txn`s the miner's blocks include:
+ * - `fee` includes the highest fee rates first, maximizing fees
+ * - `fifo` includes the oldest `txn`s first, regardless of fee rate
+ * - `empty` includes no `txn`s
+ * A positive `SoftLimit` caps the size in bytes of the miner's blocks below
+ * the maximum block size, like the 750KB default of older Bitcoin Core
+ * releases.  `Vote` is the maximum block size the miner's blocks vote for
+ * under a `VotingBlockSize`, 0 to vote for the current size.
+ */
+type Miner struct {
+	Name      string  `json:"name"`
+	Hashrate  float64 `json:"hashrate"`
+	Policy    string  `json:"policy"`
+	SoftLimit float64 `json:"soft_limit,omitempty"`
+	Vote      float64 `json:"vote,omitempty"`
+}
+
+/**
+ * `SPVMining`
+ *
+ * Models miners that start mining on a new block before validating it, and
+ * can only mine empty blocks until they do.  A block found within `Window`
+ * seconds of the previous block is empty with probability `Probability`,
+ * whatever the template policy of its `Miner`.
+ */
+type SPVMining struct {
+	Window      float64 `json:"window"`
+	Probability float64 `json:"probability"`
+}
+
+/**
+ * Verifies that the `Window` is positive and the `Probability` is in [0, 1].
+ *
+ * @return - nil if the `SPVMining` is valid, otherwise the first problem
+ */
+func (spv SPVMining) Validate() error {
+	if spv.Window <= 0.0 {
+		return fmt.Errorf("SPV mining window %f must be positive", spv.Window)
+	}
+	if spv.Probability < 0.0 || spv.Probability > 1.0 {
+		return fmt.Errorf("SPV mining probability %f is not in [0, 1]", spv.Probability)
+	}
+	return nil
+}
+
+/**
+ * Decides whether a block is mined empty before the previous block was
+ * validated.  Only blocks within the `Window` draw a random number.
+ *
+ * @param interval - The time in seconds since the previous block
+ * @param r - Draws a uniform random number in [0, 1)
+ *
+ * @return - Whether the block is empty
+ */
+func (spv *SPVMining) validating(interval float64, r func() float64) bool {
+	return spv != nil && interval < spv.Window && r() < spv.Probability
+}
+
+/**
+ * Builds the unnamed miner used when a simulation has a single fee
+ * maximizing miner, so a single miner draws no random numbers.
+ *
+ * @return - The default `Miner`
+ */
+func defaultMiner() Miner {
+	return Miner{Hashrate: 1.0, Policy: TEMPLATE_FEE}
+}
+
+/**
+ * Verifies that the `Miner` has a name, a share of the hashrate and a known
+ * `Policy`.
+ *
+ * @return - nil if the `Miner` is valid, otherwise the first problem
+ */
+func (m Miner) Validate() error {
+	if !TXN_CLASS_NAME.MatchString(m.Name) {
+		return fmt.Errorf("miner name %q must only contain letters, digits and underscores", m.Name)
+	}
+	if m.Hashrate <= 0.0 || math.IsInf(m.Hashrate, 0) || math.IsNaN(m.Hashrate) {
+		return fmt.Errorf("miner %q hashrate must be positive", m.Name)
+	}
+	switch m.Policy {
+	case TEMPLATE_FEE, TEMPLATE_FIFO:
+	case TEMPLATE_EMPTY:
+		if m.SoftLimit > 0.0 {
+			return fmt.Errorf("miner %q mines empty blocks and cannot have a soft limit", m.Name)
+		}
+	default:
+		return fmt.Errorf("miner %q has unknown policy %q", m.Name, m.Policy)
+	}
+	if m.SoftLimit < 0.0 {
+		return fmt.Errorf("miner %q soft limit must not be negative", m.Name)
+	}
+	if m.Vote != 0.0 && m.Vote < BITCOIN_TRANSACTION_SIZE {
+		return fmt.Errorf("miner %q votes for blocks that cannot fit a single txn", m.Name)
+	}
+	return nil
+}
+
+/**
+ * Verifies each `Miner` and that their names are unique.
+ *
+ * @param miners - The `Miner`s of a simulation
+ *
+ * @return - nil if the `Miner`s are valid, otherwise the first problem
+ */
+func ValidateMiners(miners []Miner) error {
+	if len(miners) == 0 {
+		return fmt.Errorf("at least one miner is required")
+	}
+
+	names := map[string]bool{}
+	for _, m := range miners {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if names[m.Name] {
+			return fmt.Errorf("duplicate miner name %q", m.Name)
+		}
+		names[m.Name] = true
+	}
+	return nil
+}
+
+/**
+ * Builds the miner's block template from the pending `txn`s, removing the
+ * included `txn`s from `pool`.
+ *
+ * Fee maximizing miners without a `SoftLimit` exceed the maximum block size
+ * when the fees outweigh the `Penalty`, if there is one.
+ *
+ * @param pool - The pending `txn`s
+ * @param maxSize - The maximum size of the block in bytes
+ * @param p - The `Penalty` of larger blocks, or nil
+ *
+ * @return - The `txn`s included in the block and their total size
+ */
+func (m Miner) fillBlock(pool *mempool, maxSize float64, p *Penalty) ([]txn, float64) {
+	if m.SoftLimit > 0.0 {
+		maxSize = math.Min(maxSize, m.SoftLimit)
+		p = nil
+	}
+
+	switch m.Policy {
+	case TEMPLATE_FIFO:
+		return pool.fillBlockOldest(maxSize)
+	case TEMPLATE_EMPTY:
+		return nil, 0.0
+	}
+	if p != nil {
+		return pool.fillBlockWithPenalty(maxSize, p)
+	}
+	return pool.fillBlock(maxSize)
+}
+
+/**
+ * Chooses the miner of the next block in proportion to the hashrates.  A
+ * single miner is chosen without drawing a random number.
+ *
+ * @param miners - The `Miner`s of the simulation
+ * @param r - Draws a uniform random number in [0, 1)
+ *
+ * @return - The index of the miner that finds the block
+ */
+func chooseMiner(miners []Miner, r func() float64) int {
+	if len(miners) == 1 {
+		return 0
+	}
+
+	total := 0.0
+	for _, m := range miners {
+		total += m.Hashrate
+	}
+	x := r() * total
+	for i, m := range miners {
+		x -= m.Hashrate
+		if x < 0.0 {
+			return i
+		}
+	}
+	return len(miners) - 1
+}
@@ -0,0 +1,116 @@
+package bitcoin_load_spike
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestValidateMiners(t *testing.T) {
+	valid := Miner{Name: "pool", Hashrate: 0.5, Policy: TEMPLATE_FEE, SoftLimit: 750000.0}
+	if err := ValidateMiners([]Miner{valid}); err != nil {
+		t.Error("Expected miner to be valid, got", err)
+	}
+
+	invalid := map[string][]Miner{
+		"no miners":        nil,
+		"unnamed miner":    []Miner{defaultMiner()},
+		"duplicate":        []Miner{valid, valid},
+		"no hashrate":      []Miner{Miner{Name: "idle", Policy: TEMPLATE_FEE}},
+		"unknown policy":   []Miner{Miner{Name: "lifo", Hashrate: 1.0, Policy: "lifo"}},
+		"empty soft limit": []Miner{Miner{Name: "spv", Hashrate: 1.0, Policy: TEMPLATE_EMPTY, SoftLimit: 1000.0}},
+		"tiny vote":        []Miner{Miner{Name: "small", Hashrate: 1.0, Policy: TEMPLATE_FEE, Vote: 100.0}},
+	}
+	for name, miners := range invalid {
+		if ValidateMiners(miners) == nil {
+			t.Error("Expected", name, "to be invalid")
+		}
+	}
+}
+
+func TestMinerFillBlock(t *testing.T) {
+	newPool := func() *mempool {
+		pool := newMempool()
+		for i, next := range newTestTxns(4) {
+			// Later txns pay more
+			next.feeRate = float64(i + 1)
+			pool.add(next)
+		}
+		return pool
+	}
+	maxSize := 2 * BITCOIN_TRANSACTION_SIZE
+
+	fee, _ := Miner{Name: "fee", Hashrate: 1.0, Policy: TEMPLATE_FEE}.fillBlock(newPool(), maxSize, nil)
+	if len(fee) != 2 || fee[0].time != 3.0 || fee[1].time != 2.0 {
+		t.Error("Expected the highest fee rates first, got", fee)
+	}
+
+	pool := newPool()
+	fifo, size := Miner{Name: "fifo", Hashrate: 1.0, Policy: TEMPLATE_FIFO}.fillBlock(pool, maxSize, nil)
+	if len(fifo) != 2 || fifo[0].time != 0.0 || fifo[1].time != 1.0 || size != maxSize {
+		t.Error("Expected the oldest txns first, got", fifo)
+	}
+	if pool.Len() != 2 || pool.Size() != maxSize {
+		t.Error("Expected 2 txns to remain, got", pool.Len())
+	}
+	if next, _ := pool.fillBlock(maxSize); len(next) != 2 || next[0].time != 3.0 {
+		t.Error("Expected the remaining txns to keep their fee priority, got", next)
+	}
+
+	empty, _ := Miner{Name: "spv", Hashrate: 1.0, Policy: TEMPLATE_EMPTY}.fillBlock(newPool(), maxSize, nil)
+	if len(empty) != 0 {
+		t.Error("Expected an empty block, got", empty)
+	}
+
+	capped, _ := Miner{Name: "capped", Hashrate: 1.0, Policy: TEMPLATE_FEE, SoftLimit: 1.5 * BITCOIN_TRANSACTION_SIZE}.fillBlock(newPool(), maxSize, nil)
+	if len(capped) != 1 {
+		t.Error("Expected the soft limit to cap the block, got", capped)
+	}
+}
+
+func TestChooseMiner(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	miners := []Miner{
+		Miner{Name: "large", Hashrate: 3.0, Policy: TEMPLATE_FEE},
+		Miner{Name: "small", Hashrate: 1.0, Policy: TEMPLATE_FEE},
+	}
+
+	found := make([]int, len(miners))
+	n := 100000
+	for i := 0; i < n; i++ {
+		found[chooseMiner(miners, r.Float64)]++
+	}
+	if share := float64(found[0]) / float64(n); share < 0.74 || share > 0.76 {
+		t.Error("Expected the large miner to find 75% of the blocks, got", share)
+	}
+
+	// The large miner holds the first 75% of the draws
+	if chooseMiner(miners, draws(0.74)) != 0 || chooseMiner(miners, draws(0.76)) != 1 {
+		t.Error("Expected the draw to pick the miner by cumulative hashrate")
+	}
+
+	if chooseMiner([]Miner{defaultMiner()}, func() float64 { panic("drew a random number") }) != 0 {
+		t.Error("Expected the only miner to find every block")
+	}
+}
+
+func TestSPVMiningValidating(t *testing.T) {
+	noDraw := func() float64 { panic("drew a random number") }
+	var disabled *SPVMining
+	if disabled.validating(10.0, noDraw) {
+		t.Error("Expected no empty blocks without SPV mining")
+	}
+
+	spv := &SPVMining{Window: 60.0, Probability: 0.5}
+	if spv.validating(120.0, noDraw) {
+		t.Error("Expected blocks after the window to be validated")
+	}
+	if !spv.validating(30.0, func() float64 { return 0.4 }) || spv.validating(30.0, func() float64 { return 0.6 }) {
+		t.Error("Expected blocks within the window to be empty with the given probability")
+	}
+
+	for _, invalid := range []SPVMining{SPVMining{Window: 0.0, Probability: 0.5}, SPVMining{Window: 60.0, Probability: 1.5}} {
+		if invalid.Validate() == nil {
+			t.Error("Expected", invalid, "to be invalid")
+		}
+	}
+}
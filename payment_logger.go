@@ -0,0 +1,126 @@
+package bitcoin_load_spike
+
+import "fmt"
+
+/**
+ * `PaymentLogger`
+ *
+ * Records the time from the arrival of each payment until the `txn` carrying
+ * it is included in a block.  A batch confirms all of its payments at once,
+ * each after waiting for the batch to be broadcast, while every other `txn`
+ * is a single payment.  The confirmation times are stored in a
+ * `CumulativeLogger`, so the output has the same format as the cumulative
+ * output.  Payments of unconfirmed batches are counted as censored, as are
+ * payments still waiting for their batch to be broadcast at the end of an
+ * iteration.
+ */
+type PaymentLogger struct {
+	cumulative *CumulativeLogger
+	open       []txn
+}
+
+/**
+ * Initializes a new `PaymentLogger`
+ *
+ * @param cl - The `CumulativeLogger` that records the confirmation times
+ *
+ * @return - The new `PaymentLogger`
+ */
+func newPaymentLogger(cl *CumulativeLogger) *PaymentLogger {
+	return &PaymentLogger{cumulative: cl}
+}
+
+/**
+ * @return - The specified prefix for the output file.
+ */
+func (pl PaymentLogger) FilePrefix() string {
+	return pl.cumulative.FilePrefix()
+}
+
+/**
+ * @return - The file extension for `PaymentLogger` output
+ */
+func (pl PaymentLogger) FileExtension() string {
+	return pl.cumulative.FileExtension()
+}
+
+/**
+ * Records the confirmation time of every payment of `t`.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (pl *PaymentLogger) Log(blockTimestamp float64, t txn) {
+	if t.payments == nil {
+		pl.cumulative.Log(blockTimestamp, t)
+		return
+	}
+
+	for _, p := range t.payments {
+		payment := t
+		payment.time = p
+		pl.cumulative.Log(blockTimestamp, payment)
+	}
+}
+
+/**
+ * Records a batch that had not been broadcast when the iteration ended, to
+ * be censored by `EndIteration`.
+ *
+ * @param t - The open batch
+ */
+func (pl *PaymentLogger) LogOpenBatch(t txn) {
+	pl.open = append(pl.open, t)
+}
+
+/**
+ * Censors the payments of the `txn`s that remain in the `mempool`, and those
+ * of the open batches that arrived before `endTimestamp`.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (pl *PaymentLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	payments := newMempool()
+	for _, t := range pool.txns {
+		if t.payments == nil {
+			payments.add(t)
+			continue
+		}
+		for _, p := range t.payments {
+			payment := t
+			payment.time = p
+			payments.add(payment)
+		}
+	}
+	for _, t := range pl.open {
+		for _, p := range t.payments {
+			if p < endTimestamp {
+				payment := t
+				payment.time = p
+				payments.add(payment)
+			}
+		}
+	}
+	pl.open = nil
+
+	pl.cumulative.EndIteration(endTimestamp, payments)
+}
+
+/**
+ * Accumulates the file contents for each spike.
+ *
+ * @return - The file contents for each `Spike` in the `SpikeProfile`
+ */
+func (pl *PaymentLogger) Outputs() []string {
+	fmt.Println("[PaymentLogger]: confirmation times of payments")
+	return pl.cumulative.Outputs()
+}
+
+/**
+ * Clears the logging state.
+ */
+func (pl *PaymentLogger) Reset() {
+	pl.cumulative.Reset()
+	pl.open = nil
+}
@@ -0,0 +1,36 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestPaymentLogger(t *testing.T) {
+	pl := newPaymentLogger(&CumulativeLogger{plots: []*cumulativePlot{newCumulativePlot()}})
+
+	batch := newTestTxn(600.0, 0)
+	batch.payments = []float64{0.0, 300.0, 599.0}
+	pl.Log(1200.0, batch)
+	pl.Log(1200.0, newTestTxn(900.0, 0))
+
+	pool := newMempool()
+	unconfirmed := newTestTxn(1800.0, 0)
+	unconfirmed.payments = []float64{1300.0, 1700.0}
+	pool.add(unconfirmed)
+	// Only the payments that arrived before the end are pending
+	open := newTestTxn(2400.0, 0)
+	open.payments = []float64{1900.0, 2100.0}
+	pl.LogOpenBatch(open)
+	pl.EndIteration(2000.0, pool)
+
+	plot := pl.cumulative.plots[0]
+	if plot.txnCount != 4 {
+		t.Error("Expected 4 confirmed payments, got", plot.txnCount)
+	}
+	if plot.censoredCount != 3 {
+		t.Error("Expected 3 censored payments, got", plot.censoredCount)
+	}
+	if b := confirmationBucket(100.0); plot.censored[b] != 1 {
+		t.Error("Expected the open batch's payment to be censored at its age, got", plot.censored[b])
+	}
+	if b := confirmationBucket(1200.0); plot.buckets[b] != 1 {
+		t.Error("Expected the first payment to wait for the batch, got", plot.buckets[b])
+	}
+}
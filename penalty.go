@@ -0,0 +1,87 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+)
+
+// Shapes of the `Penalty` as a function of the excess block size
+const (
+	PENALTY_QUADRATIC = "quadratic"
+	PENALTY_LINEAR    = "linear"
+)
+
+/**
+ * `Penalty`
+ *
+ * A flexible cap on the block size, modeled on Monero's block reward penalty
+ * and on flexcap proposals.  A block may exceed the maximum block size, up to
+ * `MaxFactor` times it, if its miner forfeits part of the block reward.  The
+ * `Kind` sets how the penalty grows with the excess size:
+ * - `quadratic`, Monero's, forfeits `Reward * (size / maxSize - 1)^2`
+ * - `linear` forfeits `Reward * (size / maxSize - 1)`
+ * satoshis, and an empty `Kind` is `quadratic`.  Fee maximizing miners
+ * include a `txn` beyond the maximum size only if its fee outweighs the
+ * increase in the penalty.
+ */
+type Penalty struct {
+	Kind      string  `json:"kind,omitempty"`
+	Reward    float64 `json:"reward"`
+	MaxFactor float64 `json:"max_factor"`
+}
+
+/**
+ * Verifies that the `Kind` is known, the `Reward` is positive and the
+ * `MaxFactor` is above 1.
+ *
+ * @return - nil if the `Penalty` is valid, otherwise the first problem
+ */
+func (p Penalty) Validate() error {
+	switch p.Kind {
+	case "", PENALTY_QUADRATIC, PENALTY_LINEAR:
+	default:
+		return fmt.Errorf("unknown penalty kind %q", p.Kind)
+	}
+	if p.Reward <= 0.0 || math.IsInf(p.Reward, 0) {
+		return fmt.Errorf("penalty reward %f must be positive", p.Reward)
+	}
+	if p.MaxFactor <= 1.0 {
+		return fmt.Errorf("penalty maximum factor %f must be greater than 1", p.MaxFactor)
+	}
+	return nil
+}
+
+/**
+ * @param size - The size of the block in bytes
+ * @param maxSize - The maximum block size in bytes without a penalty
+ *
+ * @return - The penalty in satoshis of the block, 0 without a `Penalty`
+ */
+func (p *Penalty) cost(size, maxSize float64) float64 {
+	if p == nil || size <= maxSize {
+		return 0.0
+	}
+
+	excess := size/maxSize - 1.0
+	if p.Kind == PENALTY_LINEAR {
+		return p.Reward * excess
+	}
+	return p.Reward * excess * excess
+}
+
+/**
+ * Decides whether a block of `size` bytes should include `t`, which pays
+ * for itself if the block stays within `maxSize` bytes.
+ *
+ * @param size - The size of the block without `t` in bytes
+ * @param t - The next `txn` by fee rate
+ * @param maxSize - The maximum block size in bytes without a penalty
+ *
+ * @return - Whether the fee of `t` outweighs the increase in the penalty
+ */
+func (p *Penalty) worth(size float64, t txn, maxSize float64) bool {
+	if size+t.size > p.MaxFactor*maxSize {
+		return false
+	}
+	return t.feeRate*t.size > p.cost(size+t.size, maxSize)-p.cost(size, maxSize)
+}
@@ -0,0 +1,73 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestPenaltyCost(t *testing.T) {
+	var disabled *Penalty
+	if cost := disabled.cost(2000.0, 1000.0); cost != 0.0 {
+		t.Error("Expected no penalty without a Penalty, got", cost)
+	}
+
+	p := &Penalty{Reward: 1e6, MaxFactor: 2.0}
+	expected := map[float64]float64{500.0: 0.0, 1000.0: 0.0, 1500.0: 250000.0, 2000.0: 1e6}
+	for size, cost := range expected {
+		if c := p.cost(size, 1000.0); c != cost {
+			t.Error("Expected penalty", cost, "for", size, "bytes, got", c)
+		}
+	}
+
+	linear := &Penalty{Kind: PENALTY_LINEAR, Reward: 1e6, MaxFactor: 2.0}
+	expected = map[float64]float64{1000.0: 0.0, 1500.0: 5e5, 2000.0: 1e6}
+	for size, cost := range expected {
+		if c := linear.cost(size, 1000.0); c != cost {
+			t.Error("Expected linear penalty", cost, "for", size, "bytes, got", c)
+		}
+	}
+
+	invalid := []Penalty{
+		Penalty{Reward: 0.0, MaxFactor: 2.0},
+		Penalty{Reward: 1e6, MaxFactor: 1.0},
+		Penalty{Kind: "cubic", Reward: 1e6, MaxFactor: 2.0},
+	}
+	for _, invalid := range invalid {
+		if invalid.Validate() == nil {
+			t.Error("Expected", invalid, "to be invalid")
+		}
+	}
+}
+
+func TestMempoolFillBlockWithPenalty(t *testing.T) {
+	pool := newMempool()
+	for i, feeRate := range []float64{1000.0, 400.0, 100.0, 10.0} {
+		pool.add(txn{time: float64(i), size: 100.0, feeRate: feeRate})
+	}
+
+	// The first txn beyond the limit costs a penalty of 5000 satoshis, which
+	// its fee outweighs, but the next raises the penalty to 20000 satoshis
+	p := &Penalty{Reward: 2e4, MaxFactor: 2.0}
+	included, size := pool.fillBlockWithPenalty(200.0, p)
+	if len(included) != 3 || size != 300.0 {
+		t.Fatal("Expected 3 txns in a penalized block, got", len(included))
+	}
+	if included[2].feeRate != 100.0 {
+		t.Error("Expected the highest fee rates first, got", included)
+	}
+	if pool.Len() != 1 {
+		t.Error("Expected 1 txn to remain, got", pool.Len())
+	}
+}
+
+func TestMempoolFillBlockWithLinearPenalty(t *testing.T) {
+	pool := newMempool()
+	for i, feeRate := range []float64{1000.0, 400.0, 150.0, 50.0} {
+		pool.add(txn{time: float64(i), size: 100.0, feeRate: feeRate})
+	}
+
+	// Every byte beyond the limit costs 100 satoshis, so only the txns
+	// paying more than 100 satoshis per byte are worth including
+	p := &Penalty{Kind: PENALTY_LINEAR, Reward: 2e4, MaxFactor: 2.0}
+	included, size := pool.fillBlockWithPenalty(200.0, p)
+	if len(included) != 3 || size != 300.0 || included[2].feeRate != 150.0 {
+		t.Error("Expected the txns above the marginal penalty, got", included)
+	}
+}
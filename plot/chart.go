@@ -0,0 +1,304 @@
+/**
+ * Package plot renders line charts of the simulation outputs to SVG and PNG
+ * without any dependencies outside of the standard library.
+ */
+package plot
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Chart dimensions in pixels
+const WIDTH = 960
+const HEIGHT = 540
+const MARGIN_LEFT = 110
+const MARGIN_RIGHT = 250
+const MARGIN_TOP = 50
+const MARGIN_BOTTOM = 60
+
+// Approximate number of ticks on a linear axis
+const NUM_TICKS = 6
+
+/**
+ * `Series`
+ *
+ * A single line of a `Chart`, `X` and `Y` must have the same length.
+ */
+type Series struct {
+	Label string
+	X     []float64
+	Y     []float64
+}
+
+/**
+ * `Chart`
+ *
+ * A line chart with optional log scaled axes and a legend of the `Series`
+ * labels.  Points that cannot be shown on a log scaled axis are skipped.
+ */
+type Chart struct {
+	Title  string
+	XLabel string
+	YLabel string
+	LogX   bool
+	LogY   bool
+	Series []Series
+}
+
+/**
+ * Line colors, cycled if there are more series than colors
+ */
+var palette = []color.RGBA{
+	{31, 119, 180, 255},
+	{255, 127, 14, 255},
+	{44, 160, 44, 255},
+	{214, 39, 40, 255},
+	{148, 103, 189, 255},
+	{140, 86, 75, 255},
+	{227, 119, 194, 255},
+	{127, 127, 127, 255},
+}
+
+var black = color.RGBA{0, 0, 0, 255}
+var grey = color.RGBA{220, 220, 220, 255}
+
+/**
+ * Text alignment relative to the anchor point
+ */
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+/**
+ * A point in pixel coordinates
+ */
+type point struct {
+	x, y float64
+}
+
+/**
+ * Drawing operations implemented by the SVG and PNG renderers.  Text is
+ * vertically centered on `y`.
+ */
+type canvas interface {
+	line(from, to point, c color.RGBA)
+	polyline(points []point, c color.RGBA)
+	text(p point, s string, a anchor, c color.RGBA)
+	verticalText(p point, s string, c color.RGBA)
+}
+
+/**
+ * Maps data values onto one axis of the plot area
+ */
+type axis struct {
+	min, max   float64
+	log        bool
+	start, end float64
+}
+
+/**
+ * @return - The pixel coordinate of `v`
+ */
+func (a axis) scale(v float64) float64 {
+	min, max := a.min, a.max
+	if a.log {
+		v, min, max = math.Log10(v), math.Log10(min), math.Log10(max)
+	}
+	return a.start + (v-min)/(max-min)*(a.end-a.start)
+}
+
+/**
+ * @return - Whether `v` can be shown on the axis
+ */
+func (a axis) valid(v float64) bool {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return false
+	}
+	return !a.log || v > 0.0
+}
+
+/**
+ * @return - The tick values within the axis range
+ */
+func (a axis) ticks() (ticks []float64) {
+	if a.log {
+		for e := math.Floor(math.Log10(a.min)); e <= math.Ceil(math.Log10(a.max)); e++ {
+			v := math.Pow(10.0, e)
+			if v >= a.min*(1-1e-9) && v <= a.max*(1+1e-9) {
+				ticks = append(ticks, v)
+			}
+		}
+		return
+	}
+
+	step := niceStep((a.max - a.min) / NUM_TICKS)
+	for v := math.Ceil(a.min/step) * step; v <= a.max+step*1e-9; v += step {
+		ticks = append(ticks, v)
+	}
+	return
+}
+
+/**
+ * Rounds `raw` up to 1, 2 or 5 times a power of 10.
+ *
+ * @return - A readable tick spacing
+ */
+func niceStep(raw float64) float64 {
+	magnitude := math.Pow(10.0, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*magnitude >= raw {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+/**
+ * @return - A short label for tick value `v`
+ */
+func tickLabel(v float64) string {
+	if v != 0.0 && (math.Abs(v) >= 1e4 || math.Abs(v) < 1e-3) {
+		return strconv.FormatFloat(v, 'e', -1, 64)
+	}
+	return fmt.Sprintf("%g", math.Round(v*1e6)/1e6)
+}
+
+/**
+ * Computes the data range of both axes.
+ *
+ * @return - The x and y axes and any error if there is nothing to plot
+ */
+func (c *Chart) axes() (x, y axis, err error) {
+	x = axis{math.Inf(1), math.Inf(-1), c.LogX, MARGIN_LEFT, WIDTH - MARGIN_RIGHT}
+	y = axis{math.Inf(1), math.Inf(-1), c.LogY, HEIGHT - MARGIN_BOTTOM, MARGIN_TOP}
+
+	for _, s := range c.Series {
+		if len(s.X) != len(s.Y) {
+			return x, y, fmt.Errorf("series %q has %d x values and %d y values", s.Label, len(s.X), len(s.Y))
+		}
+		for i := range s.X {
+			if !x.valid(s.X[i]) || !y.valid(s.Y[i]) {
+				continue
+			}
+			x.min, x.max = math.Min(x.min, s.X[i]), math.Max(x.max, s.X[i])
+			y.min, y.max = math.Min(y.min, s.Y[i]), math.Max(y.max, s.Y[i])
+		}
+	}
+
+	if x.min > x.max {
+		return x, y, errors.New("chart has no points to plot")
+	}
+	x.min, x.max = widen(x.min, x.max, x.log)
+	y.min, y.max = widen(y.min, y.max, y.log)
+
+	return
+}
+
+/**
+ * Widens an empty range so that it can be scaled.
+ */
+func widen(min, max float64, log bool) (float64, float64) {
+	if min < max {
+		return min, max
+	}
+	if log {
+		return min / 10.0, max * 10.0
+	}
+	return min - 1.0, max + 1.0
+}
+
+/**
+ * Draws the axes, grid, series and legend onto `cv`.
+ */
+func (c *Chart) draw(cv canvas) error {
+	x, y, err := c.axes()
+	if err != nil {
+		return err
+	}
+
+	// Grid and tick labels
+	for _, v := range x.ticks() {
+		px := x.scale(v)
+		cv.line(point{px, y.start}, point{px, y.end}, grey)
+		cv.text(point{px, y.start + 16}, tickLabel(v), anchorMiddle, black)
+	}
+	for _, v := range y.ticks() {
+		py := y.scale(v)
+		cv.line(point{x.start, py}, point{x.end, py}, grey)
+		cv.text(point{x.start - 8, py}, tickLabel(v), anchorEnd, black)
+	}
+
+	// Frame
+	cv.polyline([]point{
+		{x.start, y.end}, {x.end, y.end}, {x.end, y.start}, {x.start, y.start}, {x.start, y.end},
+	}, black)
+
+	// Series, split wherever a point cannot be shown
+	for i, s := range c.Series {
+		color := palette[i%len(palette)]
+		points := []point{}
+		for j := range s.X {
+			if !x.valid(s.X[j]) || !y.valid(s.Y[j]) {
+				cv.polyline(points, color)
+				points = []point{}
+				continue
+			}
+			points = append(points, point{x.scale(s.X[j]), y.scale(s.Y[j])})
+		}
+		cv.polyline(points, color)
+	}
+
+	// Titles
+	cv.text(point{(x.start + x.end) / 2, MARGIN_TOP / 2}, c.Title, anchorMiddle, black)
+	cv.text(point{(x.start + x.end) / 2, HEIGHT - MARGIN_BOTTOM/3}, c.XLabel, anchorMiddle, black)
+	cv.verticalText(point{MARGIN_LEFT / 6, (y.start + y.end) / 2}, c.YLabel, black)
+
+	// Legend
+	for i, s := range c.Series {
+		py := float64(MARGIN_TOP + 10 + 22*i)
+		px := x.end + 16
+		cv.line(point{px, py}, point{px + 24, py}, palette[i%len(palette)])
+		cv.text(point{px + 32, py}, s.Label, anchorStart, black)
+	}
+
+	return nil
+}
+
+/**
+ * Renders the chart as an SVG document.
+ *
+ * @param w - The destination of the SVG document
+ *
+ * @return - Any error rendering or writing the chart
+ */
+func (c *Chart) SVG(w io.Writer) error {
+	cv := newSVGCanvas()
+	if err := c.draw(cv); err != nil {
+		return err
+	}
+	return cv.write(w)
+}
+
+/**
+ * Renders the chart as a PNG image.
+ *
+ * @param w - The destination of the PNG image
+ *
+ * @return - Any error rendering or writing the chart
+ */
+func (c *Chart) PNG(w io.Writer) error {
+	cv := newPNGCanvas()
+	if err := c.draw(cv); err != nil {
+		return err
+	}
+	return cv.write(w)
+}
@@ -0,0 +1,128 @@
+package plot
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testChart() *Chart {
+	return &Chart{
+		Title:  "Cumulative confirmation times",
+		XLabel: "confirmation time (s)",
+		YLabel: "cumulative probability",
+		LogX:   true,
+		Series: []Series{
+			{"0%: load 0.10", []float64{1, 10, 100, 1000}, []float64{0.1, 0.4, 0.9, 1.0}},
+			{"33%: load 10.00", []float64{0, 10, 1000, 10000}, []float64{0.0, 0.1, 0.5, 1.0}},
+		},
+	}
+}
+
+func TestTicks(t *testing.T) {
+	log := axis{min: 2, max: 5000, log: true}
+	if ticks := log.ticks(); !reflect.DeepEqual(ticks, []float64{10, 100, 1000}) {
+		t.Error("Expected powers of 10, got", ticks)
+	}
+
+	linear := axis{min: 0, max: 1}
+	ticks := linear.ticks()
+	if len(ticks) != 6 || ticks[0] != 0.0 || ticks[5] < 0.999 {
+		t.Error("Expected ticks every 0.2, got", ticks)
+	}
+
+	if step := niceStep(3.1); step != 5.0 {
+		t.Error("Expected step 5, got", step)
+	}
+	if label := tickLabel(10000); label != "1e+04" {
+		t.Error("Expected exponent label, got", label)
+	}
+	if label := tickLabel(1500000); label != "1.5e+06" {
+		t.Error("Expected exponent label, got", label)
+	}
+}
+
+func TestAxesSkipInvalidPoints(t *testing.T) {
+	x, y, err := testChart().axes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The point at x = 0 cannot be shown on the log axis
+	if x.min != 1 || x.max != 10000 || y.min != 0.1 || y.max != 1.0 {
+		t.Error("Unexpected axis ranges", x, y)
+	}
+
+	if _, _, err := (&Chart{LogY: true, Series: []Series{{"", []float64{1}, []float64{0}}}}).axes(); err == nil {
+		t.Error("Expected an error for a chart without points")
+	}
+	if _, _, err := (&Chart{Series: []Series{{"", []float64{1, 2}, []float64{0}}}}).axes(); err == nil {
+		t.Error("Expected an error for mismatched series")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testChart().SVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg") || strings.Count(svg, "<polyline") != 3 {
+		t.Error("Expected a frame and 2 series")
+	}
+	if !strings.Contains(svg, ">33%: load 10.00</text>") {
+		t.Error("Expected the legend to contain the spike labels")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testChart().PNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != WIDTH || bounds.Dy() != HEIGHT {
+		t.Error("Unexpected image size", bounds)
+	}
+
+	// The first series is drawn through its first point
+	x, y, _ := testChart().axes()
+	r, g, b, _ := img.At(int(x.scale(1)), int(y.scale(0.1))).RGBA()
+	if uint8(r>>8) != palette[0].R || uint8(g>>8) != palette[0].G || uint8(b>>8) != palette[0].B {
+		t.Error("Expected the first series color at its first point")
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	for r, source := range glyphSource {
+		for _, row := range source {
+			if len(row) != GLYPH_WIDTH {
+				t.Errorf("Glyph %q has a row of width %d", r, len(row))
+			}
+		}
+	}
+	if glyphFor('a') != glyphFor('A') || glyphFor('~') != glyphFor('?') {
+		t.Error("Expected lowercase and unknown characters to be mapped")
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	rows, err := ReadTable(strings.NewReader("0 | 1.000000 | 0.5\n\n1 | 10.000000 | 1.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Columns("", rows, 1, 2)
+	if !reflect.DeepEqual(s.X, []float64{1, 10}) || !reflect.DeepEqual(s.Y, []float64{0.5, 1.0}) {
+		t.Error("Unexpected series", s)
+	}
+
+	if _, err := ReadTable(strings.NewReader("0 | x\n")); err == nil {
+		t.Error("Expected an error for a non numeric column")
+	}
+}
@@ -0,0 +1,120 @@
+package plot
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Size of each font pixel in image pixels
+const FONT_SCALE = 2
+
+/**
+ * Renders a chart into an RGBA image.
+ */
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func newPNGCanvas() *pngCanvas {
+	img := image.NewRGBA(image.Rect(0, 0, WIDTH, HEIGHT))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return &pngCanvas{img}
+}
+
+/**
+ * Draws a one pixel wide line by stepping along its longer dimension.
+ */
+func (pc *pngCanvas) line(from, to point, c color.RGBA) {
+	dx, dy := to.x-from.x, to.y-from.y
+	steps := math.Ceil(math.Max(math.Abs(dx), math.Abs(dy)))
+	if steps == 0 {
+		pc.img.SetRGBA(int(math.Round(from.x)), int(math.Round(from.y)), c)
+		return
+	}
+
+	for i := 0.0; i <= steps; i++ {
+		x := from.x + dx*i/steps
+		y := from.y + dy*i/steps
+		pc.img.SetRGBA(int(math.Round(x)), int(math.Round(y)), c)
+	}
+}
+
+/**
+ * Draws each segment twice, offset by a pixel, so that series stand out
+ * from the grid.
+ */
+func (pc *pngCanvas) polyline(points []point, c color.RGBA) {
+	for i := 1; i < len(points); i++ {
+		pc.line(points[i-1], points[i], c)
+		pc.line(point{points[i-1].x, points[i-1].y + 1}, point{points[i].x, points[i].y + 1}, c)
+	}
+}
+
+/**
+ * @return - The width of `s` in pixels
+ */
+func textWidth(s string) float64 {
+	return float64(len(s) * (GLYPH_WIDTH + 1) * FONT_SCALE)
+}
+
+func (pc *pngCanvas) text(p point, s string, a anchor, c color.RGBA) {
+	x := p.x
+	switch a {
+	case anchorMiddle:
+		x -= textWidth(s) / 2
+	case anchorEnd:
+		x -= textWidth(s)
+	}
+	top := int(math.Round(p.y)) - GLYPH_HEIGHT*FONT_SCALE/2
+
+	for i, r := range []rune(s) {
+		left := int(math.Round(x)) + i*(GLYPH_WIDTH+1)*FONT_SCALE
+		pc.glyph(r, func(gx, gy int) (int, int) {
+			return left + gx, top + gy
+		}, c)
+	}
+}
+
+func (pc *pngCanvas) verticalText(p point, s string, c color.RGBA) {
+	bottom := int(math.Round(p.y + textWidth(s)/2))
+	left := int(math.Round(p.x)) - GLYPH_HEIGHT*FONT_SCALE/2
+
+	for i, r := range []rune(s) {
+		start := bottom - i*(GLYPH_WIDTH+1)*FONT_SCALE
+		pc.glyph(r, func(gx, gy int) (int, int) {
+			return left + gy, start - gx
+		}, c)
+	}
+}
+
+/**
+ * Draws the glyph of `r`, mapping each scaled glyph pixel through
+ * `position` so that text can be rotated.
+ */
+func (pc *pngCanvas) glyph(r rune, position func(gx, gy int) (int, int), c color.RGBA) {
+	rows := glyphFor(r)
+	for row := 0; row < GLYPH_HEIGHT; row++ {
+		for col := 0; col < GLYPH_WIDTH; col++ {
+			if rows[row]&(1<<uint(GLYPH_WIDTH-1-col)) == 0 {
+				continue
+			}
+			for sy := 0; sy < FONT_SCALE; sy++ {
+				for sx := 0; sx < FONT_SCALE; sx++ {
+					x, y := position(col*FONT_SCALE+sx, row*FONT_SCALE+sy)
+					pc.img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+}
+
+/**
+ * Encodes the image as a PNG.
+ */
+func (pc *pngCanvas) write(w io.Writer) error {
+	return png.Encode(w, pc.img)
+}
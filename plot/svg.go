@@ -0,0 +1,66 @@
+package plot
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+)
+
+/**
+ * Renders a chart as SVG elements.
+ */
+type svgCanvas struct {
+	elements strings.Builder
+}
+
+func newSVGCanvas() *svgCanvas {
+	return &svgCanvas{}
+}
+
+/**
+ * @return - The SVG representation of `c`
+ */
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("rgb(%d,%d,%d)", c.R, c.G, c.B)
+}
+
+func (sc *svgCanvas) line(from, to point, c color.RGBA) {
+	fmt.Fprintf(&sc.elements, "<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"%s\" />\n",
+		from.x, from.y, to.x, to.y, svgColor(c))
+}
+
+func (sc *svgCanvas) polyline(points []point, c color.RGBA) {
+	if len(points) < 2 {
+		return
+	}
+
+	coordinates := make([]string, len(points))
+	for i, p := range points {
+		coordinates[i] = fmt.Sprintf("%.2f,%.2f", p.x, p.y)
+	}
+	fmt.Fprintf(&sc.elements, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"1.5\" />\n",
+		strings.Join(coordinates, " "), svgColor(c))
+}
+
+func (sc *svgCanvas) text(p point, s string, a anchor, c color.RGBA) {
+	textAnchor := map[anchor]string{anchorStart: "start", anchorMiddle: "middle", anchorEnd: "end"}[a]
+	fmt.Fprintf(&sc.elements, "<text x=\"%.2f\" y=\"%.2f\" fill=\"%s\" text-anchor=\"%s\" dominant-baseline=\"middle\">%s</text>\n",
+		p.x, p.y, svgColor(c), textAnchor, html.EscapeString(s))
+}
+
+func (sc *svgCanvas) verticalText(p point, s string, c color.RGBA) {
+	fmt.Fprintf(&sc.elements, "<text x=\"%.2f\" y=\"%.2f\" fill=\"%s\" text-anchor=\"middle\" dominant-baseline=\"middle\" transform=\"rotate(-90 %.2f %.2f)\">%s</text>\n",
+		p.x, p.y, svgColor(c), p.x, p.y, html.EscapeString(s))
+}
+
+/**
+ * Writes the complete SVG document.
+ */
+func (sc *svgCanvas) write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"13\">\n"+
+		"<rect width=\"100%%\" height=\"100%%\" fill=\"white\" />\n%s</svg>\n",
+		WIDTH, HEIGHT, sc.elements.String())
+	return err
+}
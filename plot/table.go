@@ -0,0 +1,61 @@
+package plot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Reads the rows of a logger output, in which columns are separated by '|'.
+ * Blank lines are skipped.
+ *
+ * @param r - The contents of the logger output
+ *
+ * @return - The rows of the table and any error reading or parsing them
+ */
+func ReadTable(r io.Reader) (rows [][]float64, err error) {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		columns := strings.Split(text, "|")
+		row := make([]float64, len(columns))
+		for i, column := range columns {
+			row[i], err = strconv.ParseFloat(strings.TrimSpace(column), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+/**
+ * Builds a `Series` from two columns of a table, skipping rows that are too
+ * short.
+ *
+ * @param label - The label of the `Series`
+ * @param rows - The rows of the table
+ * @param x - The column of the x values
+ * @param y - The column of the y values
+ *
+ * @return - The new `Series`
+ */
+func Columns(label string, rows [][]float64, x, y int) Series {
+	s := Series{Label: label}
+	for _, row := range rows {
+		if len(row) <= x || len(row) <= y {
+			continue
+		}
+		s.X = append(s.X, row[x])
+		s.Y = append(s.Y, row[y])
+	}
+	return s
+}
@@ -11,6 +11,15 @@ import (
  * @return - A poisson sample for the given `rate`
  */
 func drawFromPoisson(rate float64) float64 {
-	r := rand.Float64()
+	return drawFromPoissonWith(rand.Float64(), rate)
+}
+
+/**
+ * Returns a sample from a poisson distribution for a given `rate` using the
+ * uniform random number `r` in [0, 1).
+ *
+ * @return - A poisson sample for the given `rate`
+ */
+func drawFromPoissonWith(r, rate float64) float64 {
 	return -float64(math.Log(1.0-r) / rate)
 }
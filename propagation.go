@@ -0,0 +1,145 @@
+package bitcoin_load_spike
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+ * `Propagation`
+ *
+ * Models the time a block takes to reach the other miners, `Latency` seconds
+ * plus `SecondsPerMB` seconds per million bytes of the block, so larger
+ * blocks propagate more slowly.  A block found by another miner before the
+ * first block propagated forks the chain at that height, and each branch is
+ * mined from its own `mempool`.
+ *
+ * Blocks are found at the network's rate whether or not the chain is forked,
+ * and each block of a fork extends either branch with equal probability.  If
+ * the block propagates before another one is found, its branch wins,
+ * otherwise the other branch catches up and the fork continues.  The blocks
+ * of a fork are tentative until it resolves.  If the observed branch, the one
+ * extended by the block found first, loses, nodes reorganize their chain:
+ * the blocks of the observed branch are retracted, their `txn`s return to the
+ * `mempool`, and the winning branch's blocks confirm their `txn`s at their
+ * heights.  Loggers only see the blocks of the winning chain, so every
+ * confirmation time and depth includes the delay of the reorgs.
+ */
+type Propagation struct {
+	Latency      float64 `json:"latency"`
+	SecondsPerMB float64 `json:"seconds_per_mb"`
+}
+
+/**
+ * Verifies that the delays are not negative.
+ *
+ * @return - nil if the `Propagation` is valid, otherwise the first problem
+ */
+func (p Propagation) Validate() error {
+	if p.Latency < 0.0 || p.SecondsPerMB < 0.0 {
+		return fmt.Errorf("propagation latency %f and seconds per MB %f must not be negative", p.Latency, p.SecondsPerMB)
+	}
+	return nil
+}
+
+/**
+ * @param size - The size of the block in bytes
+ *
+ * @return - The time in seconds the block takes to propagate
+ */
+func (p *Propagation) delay(size float64) float64 {
+	return p.Latency + p.SecondsPerMB*size/1e6
+}
+
+/**
+ * @param size - The size of the block in bytes
+ *
+ * @return - The probability that another block is found while the block
+ *           propagates
+ */
+func (p *Propagation) staleProbability(size float64) float64 {
+	return 1.0 - math.Exp(-p.delay(size)*BITCOIN_BLOCK_RATE)
+}
+
+/**
+ * Decides whether another block is found while a block propagates, and if
+ * so how long after the block.  Nothing is drawn without `Propagation`.
+ *
+ * @param size - The size of the block in bytes
+ * @param r - Draws a uniform random number in [0, 1)
+ *
+ * @return - The time in seconds from the block to the competing block, and
+ *           whether there is one
+ */
+func (p *Propagation) race(size float64, r func() float64) (float64, bool) {
+	if p == nil {
+		return 0.0, false
+	}
+
+	stale := p.staleProbability(size)
+	if r() >= stale {
+		return 0.0, false
+	}
+	// The time of the first arrival, given that it is within the delay
+	return -math.Log(1.0-r()*stale) / BITCOIN_BLOCK_RATE, true
+}
+
+/**
+ * `branch`
+ *
+ * A branch of the chain, with the blocks mined on it since the chain forked
+ * and the `txn`s still pending on it.
+ */
+type branch struct {
+	pool      *mempool
+	blocks    []block
+	timestamp float64
+}
+
+/**
+ * Adds a block to the tip of the branch.
+ *
+ * @param b - The block, mined from the branch's `mempool`
+ */
+func (br *branch) extend(b block) {
+	br.blocks = append(br.blocks, b)
+	br.timestamp = b.timestamp
+}
+
+/**
+ * Starts a competing branch at the parent of `b`, the block about to extend
+ * the branch, so the competing branch can include the `txn`s of `b`.
+ *
+ * @param b - The block found first at the height of the fork
+ *
+ * @return - The competing branch
+ */
+func (br *branch) fork(b block) *branch {
+	competing := &branch{pool: br.pool.clone(), timestamp: br.timestamp}
+	for _, t := range b.txns {
+		competing.pool.add(t)
+	}
+	return competing
+}
+
+/**
+ * Ends a fork of the observed chain, the branch, in favor of `winner`.  If
+ * the competing branch wins, the chain reorganizes onto it: its blocks since the
+ * fork are retracted and its `mempool` becomes that of the competing branch,
+ * where the retracted `txn`s are pending unless the winning blocks included
+ * them.
+ *
+ * @param competing - The competing branch
+ * @param winner - Either the chain or the competing branch
+ *
+ * @return - The orphaned blocks, and whether the chain reorganized
+ */
+func (br *branch) resolve(competing, winner *branch) ([]block, bool) {
+	if winner != competing {
+		return competing.blocks, false
+	}
+
+	orphaned := br.blocks
+	*br = *competing
+	return orphaned, true
+}
@@ -0,0 +1,109 @@
+package bitcoin_load_spike
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/**
+ * Returns `values` in turn, in place of a random number generator.
+ */
+func draws(values ...float64) func() float64 {
+	return func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+}
+
+func TestPropagationRace(t *testing.T) {
+	var disabled *Propagation
+	if _, forked := disabled.race(DEFAULT_BLOCK_SIZE, func() float64 { panic("drew a random number") }); forked {
+		t.Error("Expected no forks without propagation delays")
+	}
+
+	p := &Propagation{Latency: 2.0, SecondsPerMB: 10.0}
+	if delay := p.delay(2e6); delay != 22.0 {
+		t.Error("Expected 22 second delay for a 2MB block, got", delay)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	forks, n := 0, 100000
+	for i := 0; i < n; i++ {
+		offset, forked := p.race(2e6, r.Float64)
+		if !forked {
+			continue
+		}
+		forks++
+		if offset < 0.0 || offset >= 22.0 {
+			t.Fatal("Expected the competing block within the delay, got", offset)
+		}
+	}
+	rate := float64(forks) / float64(n)
+	if expected := p.staleProbability(2e6); rate < 0.95*expected || rate > 1.05*expected {
+		t.Error("Expected fork rate", expected, ", got", rate)
+	}
+
+	// The competing block is found at the median of the first arrival times
+	// within the delay
+	stale := p.staleProbability(2e6)
+	offset, forked := p.race(2e6, draws(0.0, 0.5))
+	if expected := -math.Log(1.0-0.5*stale) / BITCOIN_BLOCK_RATE; !forked || math.Abs(offset-expected) > 1e-9 {
+		t.Error("Expected a competing block after", expected, "seconds, got", offset, forked)
+	}
+	if _, forked := p.race(2e6, draws(stale)); forked {
+		t.Error("Expected no competing block once the block propagated")
+	}
+
+	if (Propagation{Latency: -1.0}).Validate() == nil {
+		t.Error("Expected a negative latency to be invalid")
+	}
+}
+
+func TestBranchReorg(t *testing.T) {
+	// The observed block confirms the higher fee txn, the competing block is
+	// empty
+	fork := func() (*branch, *branch, block) {
+		chain := &branch{pool: newMempool()}
+		high, low := newTestTxn(0.0, 0), newTestTxn(1.0, 0)
+		high.feeRate = 2 * DEFAULT_FEE_RATE
+		chain.pool.add(high)
+		chain.pool.add(low)
+
+		included, size := chain.pool.fillBlock(BITCOIN_TRANSACTION_SIZE)
+		first := block{timestamp: 600.0, size: size, numTxns: int64(len(included)), txns: included}
+		competing := chain.fork(first)
+		chain.extend(first)
+		competing.extend(block{timestamp: 610.0})
+
+		// Txns arriving during the fork are pending on both branches
+		chain.pool.add(newTestTxn(605.0, 0))
+		competing.pool.add(newTestTxn(605.0, 0))
+		return chain, competing, first
+	}
+
+	chain, competing, first := fork()
+	if chain.pool.Len() != 2 || competing.pool.Len() != 3 || competing.timestamp != 610.0 {
+		t.Fatal("Expected the competing branch to be mined without the first block, got", chain.pool.Len(), competing.pool.Len())
+	}
+
+	orphaned, reorg := chain.resolve(competing, competing)
+	if !reorg || len(orphaned) != 1 || orphaned[0].timestamp != first.timestamp {
+		t.Fatal("Expected a reorg of depth 1, got", orphaned, reorg)
+	}
+	// The retracted txn is pending again and the winning block takes its
+	// height
+	if chain.pool.Len() != 3 || len(chain.blocks) != 1 || chain.blocks[0].timestamp != 610.0 || chain.timestamp != 610.0 {
+		t.Error("Expected the chain to reorganize onto the competing branch, got", chain.pool.Len(), chain.blocks)
+	}
+
+	chain, competing, _ = fork()
+	orphaned, reorg = chain.resolve(competing, chain)
+	if reorg || len(orphaned) != 1 || orphaned[0].timestamp != 610.0 {
+		t.Error("Expected the competing block to be orphaned, got", orphaned, reorg)
+	}
+	if chain.pool.Len() != 2 || len(chain.blocks) != 1 || chain.blocks[0].numTxns != 1 {
+		t.Error("Expected the chain to keep its block, got", chain.pool.Len(), chain.blocks)
+	}
+}
@@ -0,0 +1,146 @@
+/**
+ * Package queueing computes analytical results for the bulk service queue
+ * that the simulation models under a constant load.
+ */
+package queueing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Precision of the root of the characteristic equation
+const ROOT_TOLERANCE = 1e-14
+
+/**
+ * `BatchServiceQueue`
+ *
+ * A queue with Poisson arrivals at `ArrivalRate` and service epochs at the
+ * exponentially distributed intervals of a Poisson process at `ServiceRate`.
+ * Each epoch serves up to `BatchSize` waiting customers in order of arrival,
+ * epochs occur whether or not customers are waiting.  For bitcoin, the
+ * customers are `txn`s and each epoch is a block.
+ *
+ * The stationary number waiting is geometric, P(n) = (1 - z) z^n, where z is
+ * the root in (0, 1) of mu z^(b+1) - (lambda + mu) z + lambda = 0.  An
+ * arriving customer sees n waiting and is served in epoch floor(n / b) + 1,
+ * which is geometric with success probability 1 - z^b.  A geometric sum of
+ * exponential intervals is exponential, so waiting times are exponential with
+ * rate mu (1 - z^b).
+ */
+type BatchServiceQueue struct {
+	ArrivalRate float64
+	ServiceRate float64
+	BatchSize   int64
+}
+
+/**
+ * Verifies that the queue has a stationary distribution.
+ *
+ * @return - nil if the queue is stable, otherwise the problem
+ */
+func (q BatchServiceQueue) Validate() error {
+	if q.ArrivalRate < 0.0 || q.ServiceRate <= 0.0 || q.BatchSize < 1 {
+		return errors.New("queue needs a non-negative arrival rate, a positive service rate and batch size")
+	}
+	if !q.Stable() {
+		return fmt.Errorf("queue is unstable with utilization %f, arrivals exceed the service capacity", q.Utilization())
+	}
+	return nil
+}
+
+/**
+ * @return - The ratio of the arrival rate to the maximum service rate
+ */
+func (q BatchServiceQueue) Utilization() float64 {
+	return q.ArrivalRate / (float64(q.BatchSize) * q.ServiceRate)
+}
+
+/**
+ * @return - Whether the backlog stays bounded
+ */
+func (q BatchServiceQueue) Stable() bool {
+	return q.Utilization() < 1.0
+}
+
+/**
+ * Solves the characteristic equation by bisection.  The left side is positive
+ * at 0 and negative at its minimum in (0, 1), the root lies between them.
+ *
+ * @return - The root z in [0, 1)
+ */
+func (q BatchServiceQueue) Root() float64 {
+	if q.ArrivalRate == 0.0 {
+		return 0.0
+	}
+
+	lambda, mu, b := q.ArrivalRate, q.ServiceRate, float64(q.BatchSize)
+	f := func(z float64) float64 {
+		return mu*math.Pow(z, b+1) - (lambda+mu)*z + lambda
+	}
+
+	low, high := 0.0, math.Pow((lambda+mu)/(mu*(b+1)), 1/b)
+	for high-low > ROOT_TOLERANCE {
+		mid := (low + high) / 2
+		if f(mid) > 0.0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+/**
+ * @param n - The number of waiting customers
+ *
+ * @return - The stationary probability that `n` customers are waiting
+ */
+func (q BatchServiceQueue) QueueLength(n int64) float64 {
+	z := q.Root()
+	return (1 - z) * math.Pow(z, float64(n))
+}
+
+/**
+ * @return - The stationary mean number of waiting customers
+ */
+func (q BatchServiceQueue) MeanQueueLength() float64 {
+	z := q.Root()
+	return z / (1 - z)
+}
+
+/**
+ * @return - The rate of the exponentially distributed waiting time
+ */
+func (q BatchServiceQueue) WaitingRate() float64 {
+	return q.ServiceRate * (1 - math.Pow(q.Root(), float64(q.BatchSize)))
+}
+
+/**
+ * @param t - A waiting time in seconds
+ *
+ * @return - The probability of being served within `t`
+ */
+func (q BatchServiceQueue) WaitingCDF(t float64) float64 {
+	if t <= 0.0 {
+		return 0.0
+	}
+	return 1 - math.Exp(-q.WaitingRate()*t)
+}
+
+/**
+ * @param p - The quantile in [0, 1)
+ *
+ * @return - The waiting time within which a fraction `p` are served
+ */
+func (q BatchServiceQueue) WaitingQuantile(p float64) float64 {
+	return -math.Log(1-p) / q.WaitingRate()
+}
+
+/**
+ * @return - The mean waiting time
+ */
+func (q BatchServiceQueue) MeanWaitingTime() float64 {
+	return 1 / q.WaitingRate()
+}
@@ -0,0 +1,59 @@
+package queueing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoot(t *testing.T) {
+	q := BatchServiceQueue{ArrivalRate: 0.35, ServiceRate: 1.0 / 600.0, BatchSize: 1201}
+	z := q.Root()
+
+	residual := q.ServiceRate*math.Pow(z, 1202) - (q.ArrivalRate+q.ServiceRate)*z + q.ArrivalRate
+	if z <= 0.0 || z >= 1.0 || math.Abs(residual) > 1e-12 {
+		t.Error("Expected a root in (0, 1), got", z, "with residual", residual)
+	}
+
+	// Nearly every arrival is served by the next epoch
+	if rate := q.WaitingRate(); math.Abs(rate-q.ServiceRate)/q.ServiceRate > 0.01 {
+		t.Error("Expected waiting rate close to the service rate, got", rate)
+	}
+}
+
+func TestSingleServerReduction(t *testing.T) {
+	// With batches of 1, the queue is M/M/1
+	q := BatchServiceQueue{ArrivalRate: 0.5, ServiceRate: 2.0, BatchSize: 1}
+
+	if z := q.Root(); math.Abs(z-0.25) > 1e-9 {
+		t.Error("Expected z = lambda / mu = 0.25, got", z)
+	}
+	if w := q.MeanWaitingTime(); math.Abs(w-1/1.5) > 1e-9 {
+		t.Error("Expected mean sojourn 1 / (mu - lambda), got", w)
+	}
+	if n := q.MeanQueueLength(); math.Abs(n-1.0/3.0) > 1e-9 {
+		t.Error("Expected mean queue length rho / (1 - rho), got", n)
+	}
+	if p := q.WaitingCDF(q.WaitingQuantile(0.95)); math.Abs(p-0.95) > 1e-9 {
+		t.Error("Expected the quantile to invert the CDF, got", p)
+	}
+
+	total := 0.0
+	for n := int64(0); n < 100; n++ {
+		total += q.QueueLength(n)
+	}
+	if math.Abs(total-1.0) > 1e-9 {
+		t.Error("Expected queue length probabilities to sum to 1, got", total)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (BatchServiceQueue{ArrivalRate: 2.0, ServiceRate: 1.0, BatchSize: 2}).Validate(); err == nil {
+		t.Error("Expected a queue at full utilization to be unstable")
+	}
+	if err := (BatchServiceQueue{ArrivalRate: 0.0, ServiceRate: 1.0, BatchSize: 2}).Validate(); err != nil {
+		t.Error("Expected an idle queue to be valid, got", err)
+	}
+	if z := (BatchServiceQueue{ArrivalRate: 0.0, ServiceRate: 1.0, BatchSize: 2}).Root(); z != 0.0 {
+		t.Error("Expected root 0 without arrivals, got", z)
+	}
+}
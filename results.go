@@ -0,0 +1,100 @@
+package bitcoin_load_spike
+
+/**
+ * `Results`
+ *
+ * Summarizes the confirmation times of every spike after a simulation run.
+ */
+type Results struct {
+	Iterations int64          `json:"iterations"`
+	Spikes     []SpikeResults `json:"spikes"`
+
+	plots   []*cumulativePlot
+	samples [][]IterationQuantiles
+}
+
+/**
+ * `SpikeResults`
+ *
+ * Headline metrics for the `txn`s that arrived during a single spike.
+ * Quantiles are confirmation times in seconds of the confirmed `txn`s.
+ */
+type SpikeResults struct {
+	Class     string  `json:"class,omitempty"`
+	Spike     Spike   `json:"spike"`
+	Confirmed int64   `json:"confirmed"`
+	Censored  int64   `json:"censored"`
+	Abstained int64   `json:"abstained,omitempty"`
+	Median    float64 `json:"median"`
+	P95       float64 `json:"p95"`
+	P99       float64 `json:"p99"`
+}
+
+/**
+ * Builds the `Results` from a `CumulativeLogger` that recorded every `txn`.
+ *
+ * @param iterations - The number of iterations performed
+ * @param spikes - The spikes of every `TxnClass` of the simulation
+ * @param cl - The `CumulativeLogger` with one plot per spike
+ * @param is - The `iterationSampler` of the same `txn`s
+ * @param abstained - The number of users of each spike priced out by `Bidding`
+ *
+ * @return - The summary of the simulation
+ */
+func newResults(iterations int64, spikes []classSpike, cl *CumulativeLogger, is *iterationSampler, abstained []int64) *Results {
+	results := &Results{
+		Iterations: iterations,
+		Spikes:     make([]SpikeResults, len(cl.plots)),
+		plots:      cl.plots,
+		samples:    is.samples,
+	}
+
+	for i, plot := range cl.plots {
+		results.Spikes[i] = SpikeResults{
+			Class:     spikes[i].name,
+			Spike:     spikes[i].Spike,
+			Confirmed: plot.txnCount,
+			Censored:  plot.censoredCount,
+			Abstained: abstained[i],
+			Median:    plot.quantile(0.5),
+			P95:       plot.quantile(0.95),
+			P99:       plot.quantile(0.99),
+		}
+	}
+
+	return results
+}
+
+/**
+ * @return - The fraction of the spike's `txn`s that were never confirmed
+ */
+func (sr SpikeResults) CensoredFraction() float64 {
+	total := sr.Confirmed + sr.Censored
+	if total == 0 {
+		return 0.0
+	}
+	return float64(sr.Censored) / float64(total)
+}
+
+/**
+ * Evaluates the empirical CDF of the confirmed `txn`s of a spike, at the
+ * resolution of the cumulative buckets.
+ *
+ * @param i - The index of the spike
+ * @param t - A confirmation time in seconds
+ *
+ * @return - The fraction of confirmed `txn`s with a confirmation time of at
+ *           most `t`, 0 if no `txn`s were confirmed
+ */
+func (r *Results) Probability(i int, t float64) float64 {
+	return r.plots[i].probability(t)
+}
+
+/**
+ * @param i - The index of the spike
+ *
+ * @return - The confirmation time quantiles of spike `i` in each iteration
+ */
+func (r *Results) IterationQuantiles(i int) []IterationQuantiles {
+	return r.samples[i]
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"os"
+)
+
+// Prints the analytical confirmation times of a constant load without
+// running a simulation
+func baseline(args []string) {
+	config := parseCommand("baseline", args, func(flags *flag.FlagSet, c *cliConfig) {
+		bindScenarioFlags(flags, &c.Config)
+	})
+
+	if len(config.TxnClasses) > 0 {
+		fatal(fmt.Errorf("analytical baseline requires a single transaction class, use --load"))
+	}
+	spikes := config.SpikeProfile.Spikes
+	for _, spike := range spikes {
+		if spike.Load != spikes[0].Load {
+			fatal(fmt.Errorf("analytical baseline requires a constant load, use --load"))
+		}
+	}
+	if len(spikes) == 0 {
+		fatal(fmt.Errorf("spike profile has no spikes"))
+	}
+
+	q := bls.AnalyticalQueue(config.BlockSize, spikes[0].Load)
+	fmt.Println("[Baseline]")
+	fmt.Println("     block size:", config.BlockSize)
+	fmt.Println("     load:", spikes[0].Load)
+	fmt.Println(fmt.Sprintf("     utilization: %.4f", q.Utilization()))
+	if err := q.Validate(); err != nil {
+		fmt.Println("unstable:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(fmt.Sprintf("     mean backlog: %.1f txns", q.MeanQueueLength()))
+	fmt.Println(fmt.Sprintf("     mean confirmation time: %.1f s", q.MeanWaitingTime()))
+	for _, p := range []float64{0.5, 0.95, 0.99} {
+		fmt.Println(fmt.Sprintf("     p%.f confirmation time: %.1f s", 100*p, q.WaitingQuantile(p)))
+	}
+}
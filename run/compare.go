@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// A configuration of the comparison
+type variant struct {
+	name   string
+	config *bls.Config
+}
+
+// Runs two or more configurations with the same seed and reports the
+// differences of every configuration to the first one.  The configurations
+// are either the config files given as arguments, with the flags applied on
+// top of each, or the base config with each of the `--bs` block sizes.
+func compare(args []string) {
+	var blockSizes []float64
+	bind := func(f *flag.FlagSet, c *bls.Config) {
+		bindProfileFlags(f, c)
+		bindSimulationFlags(f, c)
+		f.Func("bs", "block sizes to compare, e.g. 1048576,2097152", parseRangeInto(&blockSizes))
+	}
+	var flags *flag.FlagSet
+	config := parseCommand("compare", args, func(f *flag.FlagSet, c *cliConfig) {
+		// Keep the flag set for the config files after the flags
+		flags = f
+		bind(f, &c.Config)
+	})
+
+	variants := []variant{}
+	if flags.NArg() > 0 {
+		if len(blockSizes) > 0 {
+			fatal(fmt.Errorf("--bs cannot be combined with config files"))
+		}
+		for _, path := range flags.Args() {
+			c, err := bls.LoadConfig(path)
+			if err != nil {
+				fatal(err)
+			}
+			// The flags override the values of every file, as they override
+			// those of `--config`
+			overrides := flag.NewFlagSet("compare", flag.ExitOnError)
+			overrides.String("config", "", "")
+			overrides.Bool("dump-config", false, "")
+			bind(overrides, c)
+			overrides.Parse(args)
+			variants = append(variants, variant{path, c})
+		}
+	} else {
+		for _, bs := range blockSizes {
+			c := config.Config
+			c.BlockSize = bs
+			variants = append(variants, variant{fmt.Sprintf("bs=%.f", bs), &c})
+		}
+	}
+	if len(variants) < 2 {
+		fatal(fmt.Errorf("compare needs at least 2 configurations, give config files or a --bs list"))
+	}
+
+	// Common random numbers: every configuration draws from the same seeds,
+	// replacing the seeds of the files
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UTC().UnixNano()
+	}
+
+	simulations := make([]*bls.LoadSpikeSimulation, len(variants))
+	for i, v := range variants {
+		v.config.Seed = seed
+		v.config.Loggers = bls.LoggersConfig{SecsPerBucket: v.config.Loggers.SecsPerBucket}
+		sim, err := v.config.Simulation()
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", v.name, err))
+		}
+		simulations[i] = sim
+	}
+
+	fmt.Println("[Compare]", len(variants), "configurations, seed", seed)
+
+	// Each simulation uses two routines
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	results := make([]*bls.Results, len(variants))
+	var wg sync.WaitGroup
+	for i := range variants {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = simulations[i].Simulate()
+			fmt.Println("[Compare]: finished", variants[i].name)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < len(variants); i++ {
+		comparisons, err := bls.CompareResults(results[0], results[i])
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", variants[i].name, err))
+		}
+		printComparison(variants[0].name, variants[i].name, comparisons)
+	}
+}
+
+// Prints the quantile differences and KS distance of every spike
+func printComparison(base, other string, comparisons []bls.SpikeComparison) {
+	fmt.Println()
+	fmt.Printf("[%s] vs [%s]\n", other, base)
+	for i, sc := range comparisons {
+		if sc.Class != "" {
+			fmt.Printf("    %s (%s):\n", sc.Class, sc.Spike.Label())
+		} else {
+			fmt.Printf("    spike %d (%s):\n", i, sc.Spike.Label())
+		}
+		for _, qd := range sc.Quantiles {
+			marker := ""
+			if qd.Significant() {
+				marker = " *"
+			}
+			fmt.Printf("        p%-3.f %12.2f -> %12.2f  diff %12.2f +/- %-12s (%d pairs)%s  KS p-value %.4g\n",
+				100*qd.Quantile, qd.Base, qd.Other, qd.Mean, formatHalfWidth(qd.HalfWidth), qd.Pairs, marker, qd.KSPValue)
+		}
+		if math.IsNaN(sc.KSDistance) {
+			fmt.Println("        KS distance: no confirmed txns")
+		} else {
+			fmt.Printf("        KS distance %.4f (descriptive only)\n", sc.KSDistance)
+		}
+	}
+}
+
+func formatHalfWidth(halfWidth float64) string {
+	if math.IsInf(halfWidth, 1) {
+		return "inf"
+	}
+	return fmt.Sprintf("%.2f", halfWidth)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The config file of the command line interface, the simulation parameters
+// are shared by every command
+type cliConfig struct {
+	bls.Config
+	Sweep      sweepConfig      `json:"sweep"`
+	Plot       plotConfig       `json:"plot"`
+	Validation validationConfig `json:"validation"`
+	Metrics    string           `json:"metrics"`
+}
+
+func defaultCLIConfig() *cliConfig {
+	return &cliConfig{
+		Config:     *bls.DefaultConfig(),
+		Sweep:      defaultSweepConfig(),
+		Plot:       defaultPlotConfig(),
+		Validation: defaultValidationConfig(),
+	}
+}
+
+// Parses the arguments of a command.  The config file given by `--config` is
+// read first so that flags override its values.  `--dump-config` prints the
+// effective config and exits.
+func parseCommand(name string, args []string, bind func(*flag.FlagSet, *cliConfig)) *cliConfig {
+	config := defaultCLIConfig()
+	if path := configPath(args); path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			fatal(err)
+		}
+		if err := json.Unmarshal(contents, config); err != nil {
+			fatal(fmt.Errorf("%s: %v", path, err))
+		}
+	}
+
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.String("config", "", "path of a JSON config file, flags override its values")
+	dump := flags.Bool("dump-config", false, "print the effective config and exit")
+	bind(flags, config)
+	flags.Parse(args)
+
+	if *dump {
+		contents, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(string(contents))
+		os.Exit(0)
+	}
+
+	return config
+}
+
+// Finds the value of `--config` before the flags are parsed
+func configPath(args []string) string {
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// Binds the flags for the block size and spike profile of a simulation
+func bindScenarioFlags(flags *flag.FlagSet, c *bls.Config) {
+	flags.Float64Var(&c.BlockSize, "bs", c.BlockSize, "block size")
+	bindProfileFlags(flags, c)
+}
+
+// Binds the flags replacing the spike profile of a simulation
+func bindProfileFlags(flags *flag.FlagSet, c *bls.Config) {
+	flags.Func("load", "constant load percentage, replaces the spike profile and txn classes", func(s string) error {
+		load, err := strconv.ParseFloat(s, 64)
+		if err == nil {
+			c.UseConstantLoad(load)
+		}
+		return err
+	})
+	flags.Func("profile", "path of a JSON spike profile, replaces the spike profile and txn classes", func(path string) error {
+		profile, err := loadSpikeProfile(path)
+		if err == nil {
+			c.SpikeProfile = *profile
+			c.TxnClasses = nil
+		}
+		return err
+	})
+}
+
+// Binds the flags shared by every command that runs simulations
+func bindSimulationFlags(flags *flag.FlagSet, c *bls.Config) {
+	flags.Int64Var(&c.NumBlocks, "nb", c.NumBlocks, "number of blocks")
+	flags.Int64Var(&c.NumIterations, "ni", c.NumIterations, "number of iterations")
+	flags.Int64Var(&c.Seed, "seed", c.Seed, "seed of the simulation, 0 for a random seed")
+	flags.Float64Var(&c.AdaptiveStopping.Precision, "precision", c.AdaptiveStopping.Precision, "target relative CI half-width for adaptive stopping, 0 to disable")
+	flags.Float64Var(&c.AdaptiveStopping.Quantile, "quantile", c.AdaptiveStopping.Quantile, "confirmation time quantile tracked by adaptive stopping")
+	flags.StringVar(&c.AdaptiveStopping.Timeout, "timeout", c.AdaptiveStopping.Timeout, "maximum run time for adaptive stopping, e.g. 30m")
+	flags.Int64Var(&c.WarmUp.Blocks, "warmup-blocks", c.WarmUp.Blocks, "number of blocks during which txns are not logged")
+	flags.Float64Var(&c.WarmUp.Seconds, "warmup-secs", c.WarmUp.Seconds, "number of seconds during which txns are not logged")
+	flags.BoolVar(&c.WarmUp.MSER, "mser", c.WarmUp.MSER, "detect the warm-up period with MSER-5")
+}
+
+// Binds the flags selecting the loggers and their outputs
+func bindLoggerFlags(flags *flag.FlagSet, l *bls.LoggersConfig) {
+	flags.StringVar(&l.FilePrefix, "prefix", l.FilePrefix, "file prefix of the logger outputs")
+	flags.BoolVar(&l.Cumulative, "cl", l.Cumulative, "enable cumulative logging")
+	flags.BoolVar(&l.KaplanMeier, "km", l.KaplanMeier, "enable Kaplan-Meier cumulative logging of unconfirmed txns")
+	flags.BoolVar(&l.Analytical, "analytical", l.Analytical, "write the analytical confirmation time CDF of a constant load")
+	flags.BoolVar(&l.TimeSeries, "ts", l.TimeSeries, "enable time series logging")
+	flags.Float64Var(&l.SecsPerBucket, "tsw", l.SecsPerBucket, "time series and backlog bucket width in seconds")
+	flags.BoolVar(&l.Backlog, "backlog", l.Backlog, "enable mempool backlog logging")
+	flags.BoolVar(&l.BlockStats, "blocks", l.BlockStats, "enable block statistics logging")
+	flags.Func("depths", "comma separated confirmation depths to log, e.g. 3,6", func(list string) (err error) {
+		l.Depths, err = parseDepths(list)
+		return
+	})
+	flags.BoolVar(&l.Payments, "payments", l.Payments, "enable per payment confirmation time logging of batched txns")
+	flags.BoolVar(&l.FeeEstimates, "fees", l.FeeEstimates, "enable fee estimate hit rate and overpayment logging")
+	flags.StringVar(&l.Trace, "trace", l.Trace, "path of a CSV file for per txn traces, empty to disable")
+	flags.Float64Var(&l.TraceRate, "trace-rate", l.TraceRate, "fraction of txns written to the trace")
+}
+
+// Reads a JSON spike profile, e.g. `{"spikes": [{"percent": 0, "load": 0.1}]}`
+func loadSpikeProfile(path string) (*bls.SpikeProfile, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &bls.SpikeProfile{}
+	if err := json.Unmarshal(contents, profile); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return profile, nil
+}
+
+// Parses a comma separated list of confirmation depths
+func parseDepths(list string) (depths []int64, err error) {
+	for _, field := range strings.Split(list, ",") {
+		depth, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		depths = append(depths, depth)
+	}
+	return
+}
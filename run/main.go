@@ -1,49 +1,72 @@
 package main
 
 import (
-	"flag"
-	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"fmt"
+	"os"
 	"runtime"
+	"strings"
 )
 
-func parseFlags() (load, blockSize *float64, numBlocks, numIterations *int64) {
-	load = flag.Float64("load", 0.0, "load percentage")
-	blockSize = flag.Float64("bs", bls.DEFAULT_BLOCK_SIZE, "block size")
-	numBlocks = flag.Int64("nb", bls.DEFAULT_NUM_BLOCKS, "number of blocks")
-	numIterations = flag.Int64("ni", bls.DEFAULT_NUM_ITERATIONS, "number of iterations")
+// A subcommand of the command line interface
+type command struct {
+	name        string
+	description string
+	run         func(args []string)
+}
+
+var commands = []command{
+	{"simulate", "run a single simulation and write the logger outputs", simulate},
+	{"sweep", "run a grid of simulations and write a table of headline metrics", sweep},
+	{"compare", "compare the confirmation times of several configurations", compare},
+	{"baseline", "print the analytical confirmation times of a constant load", baseline},
+	{"validate-profile", "check that a spike profile is valid", validateProfile},
+	{"validate", "compare the model to the reference CDFs of a dataset", validateReference},
+	{"plot", "render charts from the logger outputs", plotOutputs},
+	{"serve", "serve an HTTP/JSON API to queue and poll simulations", serve},
+}
 
-	flag.Parse()
-	return
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: run <command> [--config <path>] [--dump-config] [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run `run <command> --help` for the flags of a command.  Without a command,")
+	fmt.Fprintln(os.Stderr, "the flags are passed to `simulate`.")
 }
 
 func main() {
 	// Default to two processes, this will increase after further optimizations to the `createTxns` method
 	runtime.GOMAXPROCS(2)
 
-	load, bs, nb, ns := parseFlags()
+	args := os.Args[1:]
 
-	// Use constant `SpikeProfile` if `load` is set, otherwise use custom `SpikeProfile`
-	var sp *bls.SpikeProfile
-	if *load != 0.0 {
-		sp = &bls.SpikeProfile{
-			[]bls.Spike{
-				bls.Spike{0.0, *load},
-			},
+	// Flags without a command run a single simulation
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			usage()
+			return
 		}
-	} else {
-		sp = &bls.SpikeProfile{
-			[]bls.Spike{
-				bls.Spike{0.0, 0.1},
-				bls.Spike{0.33, 10.0},
-				bls.Spike{0.67, 0.11},
-			},
+		simulate(args)
+		return
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			c.run(args[1:])
+			return
 		}
 	}
 
-	// Run simulation with appropriate `SpikeProfile`
-	bls.NewLoadSpikeSimulation(*bs, *nb, *ns).
-		UseSpikeProfile(sp).
-		AddCumulativeLogger("data/load-spike").
-		//AddTimeSeriesLogger("data/load-spike").
-		Run()
+	fmt.Fprintln(os.Stderr, "Unknown command:", args[0])
+	usage()
+	os.Exit(2)
+}
+
+// Prints `err` and exits
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
 }
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"github.com/cfromknecht/bitcoin_load_spike/plot"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The logger outputs to plot and where to write the charts
+type plotConfig struct {
+	Data    string   `json:"data"`
+	Out     string   `json:"out"`
+	Formats []string `json:"formats"`
+}
+
+func defaultPlotConfig() plotConfig {
+	return plotConfig{
+		Data:    "data",
+		Out:     "plots",
+		Formats: []string{"svg", "png"},
+	}
+}
+
+// Matches the filenames of the logger outputs,
+// `<prefix>-<spike>-<num-blocks>-<num-iterations>.<extension>`
+var outputFilename = regexp.MustCompile(`^(.+)-(\d+\.\d+:\d+\.\d+)-(\d+)-(\d+)\.(cl|an|km|tsl|bl)-dat$`)
+
+// A logger output found in the data directory
+type outputFile struct {
+	path      string
+	prefix    string
+	spike     bls.Spike
+	run       string
+	extension string
+}
+
+// The chart of a logger output type.  `group` charts have one series per
+// file of a run, other charts are drawn for each file.  Analytical outputs
+// are drawn in the cumulative chart.
+type chartKind struct {
+	name  string
+	group bool
+	chart func(files []outputFile, tables [][][]float64) plot.Chart
+}
+
+var cumulativeKind = chartKind{"cumulative", true, cumulativeChart("Cumulative confirmation times", 3)}
+
+var chartKinds = map[string]chartKind{
+	"cl":  cumulativeKind,
+	"an":  cumulativeKind,
+	"km":  {"kaplan-meier", true, cumulativeChart("Kaplan-Meier confirmation times", 4)},
+	"tsl": {"time-series", false, timeSeriesChart},
+	"bl":  {"backlog", false, backlogChart},
+}
+
+// Renders charts of the cumulative, Kaplan-Meier, time series and backlog
+// outputs found in the data directory
+func plotOutputs(args []string) {
+	config := parseCommand("plot", args, func(flags *flag.FlagSet, c *cliConfig) {
+		flags.StringVar(&c.Plot.Data, "data", c.Plot.Data, "directory of the logger outputs")
+		flags.StringVar(&c.Plot.Out, "out", c.Plot.Out, "directory of the rendered charts")
+		flags.Func("format", "comma separated chart formats, svg and/or png", func(list string) error {
+			c.Plot.Formats = strings.Split(list, ",")
+			return nil
+		})
+	}).Plot
+
+	for _, format := range config.Formats {
+		if format != "svg" && format != "png" {
+			fatal(fmt.Errorf("unknown chart format %q", format))
+		}
+	}
+
+	files, err := findOutputs(config.Data)
+	if err != nil {
+		fatal(err)
+	}
+	if len(files) == 0 {
+		fatal(fmt.Errorf("no logger outputs found in %s", config.Data))
+	}
+	if err := os.MkdirAll(config.Out, 0755); err != nil {
+		fatal(err)
+	}
+
+	// Group the files of each run, or keep each file separate
+	groups := map[string][]outputFile{}
+	names := []string{}
+	for _, f := range files {
+		kind := chartKinds[f.extension]
+		name := fmt.Sprintf("%s-%s-%s", filepath.Base(f.prefix), f.run, kind.name)
+		if !kind.group {
+			name = fmt.Sprintf("%s-%s-%s-%s", filepath.Base(f.prefix), f.spike, f.run, kind.name)
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], f)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := groups[name]
+		// Order by spike, analytical CDFs last
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].spike.Percent != group[j].spike.Percent {
+				return group[i].spike.Percent < group[j].spike.Percent
+			}
+			return group[i].extension != "an" && group[j].extension == "an"
+		})
+
+		tables := make([][][]float64, len(group))
+		for i, f := range group {
+			if tables[i], err = readTable(f.path); err != nil {
+				fatal(err)
+			}
+		}
+
+		chart := chartKinds[group[0].extension].chart(group, tables)
+		for _, format := range config.Formats {
+			path := filepath.Join(config.Out, name+"."+format)
+			if err := writeChart(&chart, format, path); err != nil {
+				fatal(err)
+			}
+			fmt.Println("Wrote", path)
+		}
+	}
+}
+
+// Lists the logger outputs in `dir`
+func findOutputs(dir string) (files []outputFile, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		match := outputFilename.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		spike, err := bls.ParseSpike(match[2])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, outputFile{
+			path:      filepath.Join(dir, entry.Name()),
+			prefix:    match[1],
+			spike:     spike,
+			run:       match[3] + "-" + match[4],
+			extension: match[5],
+		})
+	}
+	return
+}
+
+func readTable(path string) ([][]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := plot.ReadTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return rows, nil
+}
+
+func writeChart(chart *plot.Chart, format, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	render := map[string]func(io.Writer) error{"svg": chart.SVG, "png": chart.PNG}[format]
+	if err := render(f); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Plots the cumulative probability in `column` against the confirmation
+// time, with one series per spike and analytical CDF
+func cumulativeChart(title string, column int) func([]outputFile, [][][]float64) plot.Chart {
+	return func(files []outputFile, tables [][][]float64) plot.Chart {
+		chart := plot.Chart{
+			Title:  title,
+			XLabel: "confirmation time (s)",
+			YLabel: "cumulative probability",
+			LogX:   true,
+		}
+		for i, f := range files {
+			if f.extension == "an" {
+				chart.Series = append(chart.Series, plot.Columns("analytical", tables[i], 1, 2))
+				continue
+			}
+			chart.Series = append(chart.Series, plot.Columns(f.spike.Label(), tables[i], 1, column))
+		}
+		return chart
+	}
+}
+
+// Plots the mean, median and p95 confirmation times against the arrival time
+func timeSeriesChart(files []outputFile, tables [][][]float64) plot.Chart {
+	rows := hours(tables[0])
+	return plot.Chart{
+		Title:  "Confirmation times by arrival, " + files[0].spike.Label(),
+		XLabel: "arrival time (h)",
+		YLabel: "confirmation time (s)",
+		LogY:   true,
+		Series: []plot.Series{
+			plot.Columns("mean", rows, 1, 3),
+			plot.Columns("median", rows, 1, 4),
+			plot.Columns("p95", rows, 1, 5),
+		},
+	}
+}
+
+// Plots the mean number of unconfirmed txns against time
+func backlogChart(files []outputFile, tables [][][]float64) plot.Chart {
+	return plot.Chart{
+		Title:  "Mempool backlog, " + files[0].spike.Label(),
+		XLabel: "time (h)",
+		YLabel: "unconfirmed txns",
+		Series: []plot.Series{
+			plot.Columns("mean backlog", hours(tables[0]), 1, 2),
+		},
+	}
+}
+
+// Converts the bucket start times in the second column from seconds to hours
+func hours(rows [][]float64) [][]float64 {
+	converted := make([][]float64, len(rows))
+	for i, row := range rows {
+		converted[i] = append([]float64{}, row...)
+		if len(row) > 1 {
+			converted[i][1] /= 3600.0
+		}
+	}
+	return converted
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cfromknecht/bitcoin_load_spike/server"
+	"net/http"
+)
+
+// Serves the HTTP/JSON simulation API until interrupted
+func serve(args []string) {
+	addr := "localhost:8080"
+	workers := 1
+	queueSize := 16
+	parseCommand("serve", args, func(flags *flag.FlagSet, c *cliConfig) {
+		flags.StringVar(&addr, "addr", addr, "address to listen on")
+		flags.IntVar(&workers, "workers", workers, "number of simulations to run at once")
+		flags.IntVar(&queueSize, "queue", queueSize, "number of simulations that can wait for a worker")
+	})
+	if workers < 1 || queueSize < 0 {
+		fatal(fmt.Errorf("invalid number of workers or queue size"))
+	}
+
+	s := server.NewServer(workers, queueSize)
+	defer s.Close()
+
+	fmt.Println("Serving simulations on http://" + addr + "/simulations")
+	if err := http.ListenAndServe(addr, s); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"net/http"
+)
+
+// Runs a single simulation and writes the outputs of its loggers
+func simulate(args []string) {
+	config := parseCommand("simulate", args, func(flags *flag.FlagSet, c *cliConfig) {
+		bindScenarioFlags(flags, &c.Config)
+		bindSimulationFlags(flags, &c.Config)
+		bindLoggerFlags(flags, &c.Loggers)
+		flags.StringVar(&c.Metrics, "metrics", c.Metrics, "address to serve Prometheus metrics on, e.g. localhost:9090, empty to disable")
+	})
+
+	sim, err := config.Simulation()
+	if err != nil {
+		fatal(err)
+	}
+
+	if config.Metrics != "" {
+		sim.AddMetricsLogger(serveMetrics(config.Metrics))
+	}
+
+	sim.Run()
+}
+
+// Serves a new `MetricsLogger` at `/metrics` on `addr` in the background
+func serveMetrics(addr string) *bls.MetricsLogger {
+	ml := bls.NewMetricsLogger()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", ml)
+
+	fmt.Println("Serving metrics on http://" + addr + "/metrics")
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fatal(err)
+		}
+	}()
+	return ml
+}
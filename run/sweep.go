@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A single point of the sweep grid
+type sweepCombination struct {
+	blockSize float64
+	load      float64
+	spike     float64
+	duration  float64
+}
+
+// Builds the config of a combination from the shared `base` config.  The
+// `SpikeProfile` is a constant load if there is no spike, and replaces any
+// txn classes.  Loggers are disabled since only the headline metrics are kept.
+func (sc sweepCombination) config(base *bls.Config, start float64) *bls.Config {
+	spikes := []bls.Spike{bls.Spike{Percent: 0.0, Load: sc.load}}
+	if sc.spike > 0.0 {
+		spikes = append(spikes, bls.Spike{Percent: start, Load: sc.spike})
+		if start+sc.duration < 1.0 {
+			spikes = append(spikes, bls.Spike{Percent: start + sc.duration, Load: sc.load})
+		}
+	}
+
+	config := *base
+	config.BlockSize = sc.blockSize
+	config.SpikeProfile = bls.SpikeProfile{Spikes: spikes}
+	config.TxnClasses = nil
+	config.Loggers = bls.LoggersConfig{SecsPerBucket: base.Loggers.SecsPerBucket}
+
+	return &config
+}
+
+// The parameter grid of a sweep and its output
+type sweepConfig struct {
+	BlockSizes []float64 `json:"block_sizes"`
+	Loads      []float64 `json:"loads"`
+	Spikes     []float64 `json:"spikes"`
+	Durations  []float64 `json:"durations"`
+	Start      float64   `json:"start"`
+	Parallel   int       `json:"parallel"`
+	Out        string    `json:"out"`
+}
+
+func defaultSweepConfig() sweepConfig {
+	return sweepConfig{
+		BlockSizes: []float64{bls.DEFAULT_BLOCK_SIZE},
+		Loads:      []float64{0.1},
+		Spikes:     []float64{},
+		Durations:  []float64{0.34},
+		Start:      0.33,
+		Parallel:   runtime.NumCPU(),
+		Out:        "data/sweep.dat",
+	}
+}
+
+// Verifies that the sweep runs at least one simulation at a time
+func (s sweepConfig) validate() error {
+	if s.Parallel < 1 {
+		return fmt.Errorf("parallel %d must be at least 1", s.Parallel)
+	}
+	return nil
+}
+
+// Runs every combination of the parameter grid in parallel and writes one
+// row of headline metrics per combination and spike
+func sweep(args []string) {
+	config := parseCommand("sweep", args, func(flags *flag.FlagSet, c *cliConfig) {
+		bindSimulationFlags(flags, &c.Config)
+
+		s := &c.Sweep
+		flags.Func("bs", "block sizes", parseRangeInto(&s.BlockSizes))
+		flags.Func("load", "constant loads, the load before and after a spike", parseRangeInto(&s.Loads))
+		flags.Func("spike", "spike loads, empty for a constant load", parseRangeInto(&s.Spikes))
+		flags.Func("duration", "spike durations as a fraction of the blocks", parseRangeInto(&s.Durations))
+		flags.Float64Var(&s.Start, "start", s.Start, "start of the spike as a fraction of the blocks")
+		flags.IntVar(&s.Parallel, "parallel", s.Parallel, "number of simulations to run at once")
+		flags.StringVar(&s.Out, "out", s.Out, "path of the consolidated table")
+	})
+	s := config.Sweep
+	if err := s.validate(); err != nil {
+		fatal(err)
+	}
+
+	// Each simulation uses two routines
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	combinations := sweepGrid(s.BlockSizes, s.Loads, s.Spikes, s.Durations)
+	results := make([]*bls.Results, len(combinations))
+
+	// Every combination draws from the same base seed, so the combinations
+	// of a load profile see the same block intervals and arrivals
+	if config.Seed == 0 {
+		config.Seed = time.Now().UTC().UnixNano()
+	}
+
+	// Check every combination before running any of them
+	simulations := make([]*bls.LoadSpikeSimulation, len(combinations))
+	for i, c := range combinations {
+		sim, err := c.config(&config.Config, s.Start).Simulation()
+		if err != nil {
+			fatal(fmt.Errorf("%+v: %v", c, err))
+		}
+		simulations[i] = sim
+	}
+
+	fmt.Println("[Sweep]", len(combinations), "combinations, seed", config.Seed)
+
+	var wg sync.WaitGroup
+	indices := make(chan int)
+	for w := 0; w < s.Parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = simulations[i].Simulate()
+				fmt.Println(fmt.Sprintf("[Sweep]: finished %+v", combinations[i]))
+			}
+		}()
+	}
+	for i := range combinations {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	fileContents := ""
+	for i, c := range combinations {
+		for j, sr := range results[i].Spikes {
+			fileContents += fmt.Sprintf("%f | %f | %f | %f | %d | %d | %d | %f | %f | %f | %f\n",
+				c.blockSize, c.load, c.spike, c.duration,
+				j, results[i].Iterations,
+				sr.Confirmed, sr.CensoredFraction(),
+				sr.Median, sr.P95, sr.P99)
+		}
+	}
+	err := ioutil.WriteFile(s.Out, []byte(fileContents), 0644)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("[Sweep]: wrote", s.Out)
+}
+
+// Builds the full grid, the durations are only varied for spikes
+func sweepGrid(blockSizes, loads, spikes, durations []float64) (combinations []sweepCombination) {
+	if len(spikes) == 0 {
+		spikes = []float64{0.0}
+	}
+
+	for _, bs := range blockSizes {
+		for _, load := range loads {
+			for _, spike := range spikes {
+				if spike == 0.0 {
+					combinations = append(combinations, sweepCombination{bs, load, 0.0, 0.0})
+					continue
+				}
+				for _, duration := range durations {
+					combinations = append(combinations, sweepCombination{bs, load, spike, duration})
+				}
+			}
+		}
+	}
+	return
+}
+
+// Parses a comma separated list of values and `start:stop:step` ranges, e.g.
+// `0.1,0.5:1.0:0.25` is 0.1, 0.5, 0.75 and 1.0
+func parseRange(list string) (values []float64) {
+	if strings.TrimSpace(list) == "" {
+		return
+	}
+
+	for _, field := range strings.Split(list, ",") {
+		bounds := strings.Split(strings.TrimSpace(field), ":")
+		if len(bounds) == 1 {
+			values = append(values, parseFloat(bounds[0]))
+			continue
+		}
+		if len(bounds) != 3 {
+			panic("Invalid range " + field + ", expected start:stop:step")
+		}
+
+		start, stop, step := parseFloat(bounds[0]), parseFloat(bounds[1]), parseFloat(bounds[2])
+		if step <= 0.0 {
+			panic("Invalid range " + field + ", step must be positive")
+		}
+		// Allow for rounding errors when reaching `stop`
+		for i := 0; start+float64(i)*step <= stop+step*1e-9; i++ {
+			values = append(values, start+float64(i)*step)
+		}
+	}
+	return
+}
+
+// Returns a flag setter replacing `values` with a parsed range
+func parseRangeInto(values *[]float64) func(string) error {
+	return func(list string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		*values = parseRange(list)
+		return
+	}
+}
+
+func parseFloat(s string) float64 {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
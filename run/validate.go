@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"os"
+)
+
+// Checks the spike profile and the rest of the config without running a
+// simulation, exiting with a non-zero status if either is invalid
+func validateProfile(args []string) {
+	config := parseCommand("validate-profile", args, func(flags *flag.FlagSet, c *cliConfig) {
+		bindScenarioFlags(flags, &c.Config)
+		bindSimulationFlags(flags, &c.Config)
+		bindLoggerFlags(flags, &c.Loggers)
+	})
+
+	if len(config.TxnClasses) > 0 {
+		for _, class := range config.TxnClasses {
+			fmt.Println("[TxnClass]", class.Name)
+			class.SpikeProfile.PrintProfile()
+		}
+		if err := bls.ValidateTxnClasses(config.TxnClasses); err != nil {
+			fmt.Println("invalid txn classes:", err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Println("[SpikeProfile]")
+		config.SpikeProfile.PrintProfile()
+
+		if err := config.SpikeProfile.Validate(); err != nil {
+			fmt.Println("invalid spike profile:", err)
+			os.Exit(1)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		fmt.Println("valid spike profile, invalid config:", err)
+		os.Exit(1)
+	}
+	fmt.Println("valid")
+}
+
+// The reference dataset of the `validate` command
+type validationConfig struct {
+	Reference     string  `json:"reference"`
+	MaxKSDistance float64 `json:"max_ks_distance"`
+}
+
+func defaultValidationConfig() validationConfig {
+	return validationConfig{
+		Reference: bls.TRAFFIC_BULLETIN_REFERENCE,
+	}
+}
+
+// Runs the scenarios of the reference dataset and reports the KS distance of
+// each to its reference CDF, exiting with a non-zero status if any drifted
+func validateReference(args []string) {
+	config := parseCommand("validate", args, func(flags *flag.FlagSet, c *cliConfig) {
+		flags.StringVar(&c.Validation.Reference, "reference", c.Validation.Reference, "path of the reference dataset")
+		flags.Float64Var(&c.Validation.MaxKSDistance, "max-ks", c.Validation.MaxKSDistance, "largest KS distance that passes, 0 for the dataset's")
+	}).Validation
+
+	dataset, err := bls.LoadReferenceDataset(config.Reference)
+	if os.IsNotExist(err) && config.Reference == bls.TRAFFIC_BULLETIN_REFERENCE {
+		fmt.Println("the Bitcoin Traffic Bulletin's CDF points have not been transcribed to", config.Reference)
+		fmt.Println("give another dataset with --reference, e.g. reference/low_load_baseline.json for the low load baselines")
+		os.Exit(1)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if config.MaxKSDistance > 0.0 {
+		dataset.MaxKSDistance = config.MaxKSDistance
+	}
+
+	fmt.Println("[Validation]")
+	fmt.Println("     reference:", dataset.Source)
+	fmt.Println("     max KS distance:", dataset.MaxKSDistance)
+	if !dataset.Bulletin {
+		fmt.Println("     not Bitcoin Traffic Bulletin data")
+	}
+
+	failed := 0
+	for i, rs := range dataset.Scenarios {
+		result := rs.Run(dataset.MaxKSDistance)
+		status := "ok"
+		if !result.Passed {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%d/%d %-30s KS distance %.4f  %s\n", i+1, len(dataset.Scenarios), rs.Name, result.KSDistance, status)
+		if dataset.Bulletin {
+			fmt.Printf("     %s, %s\n", rs.Figure, rs.Date)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Println(failed, "of", len(dataset.Scenarios), "scenarios drifted from the reference")
+		os.Exit(1)
+	}
+}
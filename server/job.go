@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"sync"
+	"time"
+)
+
+// States of a `job`
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateCancelled = "cancelled"
+	StateFailed    = "failed"
+)
+
+/**
+ * `JobStatus`
+ *
+ * The state and progress of a submitted simulation, as returned by the API.
+ */
+type JobStatus struct {
+	ID              string     `json:"id"`
+	State           string     `json:"state"`
+	Iterations      int64      `json:"iterations"`
+	TotalIterations int64      `json:"total_iterations"`
+	Error           string     `json:"error,omitempty"`
+	Submitted       time.Time  `json:"submitted"`
+	Started         *time.Time `json:"started,omitempty"`
+	Finished        *time.Time `json:"finished,omitempty"`
+}
+
+/**
+ * `job`
+ *
+ * A simulation submitted to the `Server`.  The status is updated by the
+ * worker running the simulation and read by the handlers, so every access
+ * holds `mu`.
+ */
+type job struct {
+	mu      sync.Mutex
+	status  JobStatus
+	config  *bls.Config
+	results *bls.Results
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+/**
+ * Initializes a queued `job`
+ *
+ * @param id - The identifier of the job
+ * @param config - The validated `Config` of the simulation
+ *
+ * @return - The new `job`
+ */
+func newJob(id string, config *bls.Config) *job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &job{
+		status: JobStatus{
+			ID:              id,
+			State:           StateQueued,
+			TotalIterations: config.NumIterations,
+			Submitted:       time.Now().UTC(),
+		},
+		config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+/**
+ * @return - A copy of the job's status
+ */
+func (j *job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+/**
+ * @return - The results of a finished or cancelled job, nil otherwise
+ */
+func (j *job) Results() *bls.Results {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.results
+}
+
+/**
+ * Cancels the job.  A queued job is cancelled immediately, a running job
+ * stops after its current iteration.
+ *
+ * @return - Whether the job was still queued or running
+ */
+func (j *job) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch j.status.State {
+	case StateQueued:
+		j.status.State = StateCancelled
+		j.finish()
+	case StateRunning:
+	default:
+		return false
+	}
+	j.cancel()
+	return true
+}
+
+/**
+ * Runs the simulation unless the job was cancelled while queued.
+ */
+func (j *job) run() {
+	j.mu.Lock()
+	if j.status.State != StateQueued {
+		j.mu.Unlock()
+		return
+	}
+	j.status.State = StateRunning
+	started := time.Now().UTC()
+	j.status.Started = &started
+	j.mu.Unlock()
+
+	results, err := j.simulate()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.results = results
+	switch {
+	case err != nil:
+		j.status.State = StateFailed
+		j.status.Error = err.Error()
+	case j.ctx.Err() != nil:
+		j.status.State = StateCancelled
+	default:
+		j.status.State = StateDone
+	}
+	j.finish()
+}
+
+/**
+ * Builds and runs the simulation, reporting progress to the status.
+ * Simulations panic on invalid parameters, which fails the job.
+ *
+ * @return - The results and any error building or running the simulation
+ */
+func (j *job) simulate() (results *bls.Results, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError{r}
+		}
+	}()
+
+	lss, err := j.config.Simulation()
+	if err != nil {
+		return nil, err
+	}
+
+	results = lss.
+		UseContext(j.ctx).
+		UseProgress(func(iterations int64) {
+			j.mu.Lock()
+			j.status.Iterations = iterations
+			j.mu.Unlock()
+		}).
+		Simulate()
+	return results, nil
+}
+
+/**
+ * Records the finishing time, must hold `mu`.
+ */
+func (j *job) finish() {
+	finished := time.Now().UTC()
+	j.status.Finished = &finished
+}
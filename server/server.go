@@ -0,0 +1,230 @@
+/**
+ * Package server exposes simulations over a local HTTP/JSON API.  Submitted
+ * `Config`s are queued and run by a fixed number of workers in the same
+ * process.
+ *
+ * Routes:
+ *   POST   /simulations              submit a `Config`, returns its `JobStatus`
+ *   GET    /simulations              list the `JobStatus` of every job
+ *   GET    /simulations/<id>         poll the `JobStatus` of a job
+ *   DELETE /simulations/<id>         cancel a queued or running job
+ *   GET    /simulations/<id>/results fetch the `Results` of a finished job
+ */
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Largest accepted request body in bytes
+const MAX_CONFIG_SIZE = 1 << 20
+
+/**
+ * `Server`
+ *
+ * Serves the simulation API and owns the job queue.
+ */
+type Server struct {
+	mu     sync.Mutex
+	jobs   map[string]*job
+	order  []string
+	nextID int64
+	queue  chan *job
+	wg     sync.WaitGroup
+}
+
+/**
+ * Initializes a new `Server` and starts its workers.
+ *
+ * @param workers - The number of simulations to run at once
+ * @param queueSize - The number of jobs that can wait for a worker
+ *
+ * @return - The new `Server`
+ */
+func NewServer(workers, queueSize int) *Server {
+	if workers < 1 || queueSize < 0 {
+		panic("Cannot create Server without workers")
+	}
+
+	s := &Server{
+		jobs:  map[string]*job{},
+		queue: make(chan *job, queueSize),
+	}
+	for w := 0; w < workers; w++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for j := range s.queue {
+				j.run()
+			}
+		}()
+	}
+	return s
+}
+
+/**
+ * Cancels every job and waits for the workers to stop.  The `Server` must
+ * not handle requests afterwards.
+ */
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		j.Cancel()
+	}
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+/**
+ * Routes a request to its handler.
+ */
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "simulations" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.submit(w, r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.list(w)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.withJob(w, parts[1], s.status)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.withJob(w, parts[1], s.cancel)
+	case len(parts) == 3 && parts[2] == "results" && r.Method == http.MethodGet:
+		s.withJob(w, parts[1], s.results)
+	case len(parts) == 3 && parts[2] != "results":
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+/**
+ * Queues the `Config` in the request body, read on top of `DefaultConfig`.
+ * Loggers are disabled since the results are returned by the API rather than
+ * written to files.
+ */
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MAX_CONFIG_SIZE))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	config := bls.DefaultConfig()
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, config); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	config.Loggers = bls.LoggersConfig{SecsPerBucket: bls.DEFAULT_SECS_PER_BUCKET}
+	if err := config.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	j := newJob(strconv.FormatInt(s.nextID, 10), config)
+	select {
+	case s.queue <- j:
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, errors.New("job queue is full"))
+		return
+	}
+	s.jobs[j.status.ID] = j
+	s.order = append(s.order, j.status.ID)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, j.Status())
+}
+
+func (s *Server) list(w http.ResponseWriter) {
+	s.mu.Lock()
+	statuses := make([]JobStatus, len(s.order))
+	for i, id := range s.order {
+		statuses[i] = s.jobs[id].Status()
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) status(w http.ResponseWriter, j *job) {
+	writeJSON(w, http.StatusOK, j.Status())
+}
+
+func (s *Server) cancel(w http.ResponseWriter, j *job) {
+	if !j.Cancel() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s has already finished", j.Status().ID))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, j.Status())
+}
+
+/**
+ * Returns the `Results` of a job, cancelled jobs return the results of the
+ * completed iterations.
+ */
+func (s *Server) results(w http.ResponseWriter, j *job) {
+	status := j.Status()
+	results := j.Results()
+	if results == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", status.ID, status.State))
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+/**
+ * Looks up the job `id` and passes it to `handle`.
+ */
+func (s *Server) withJob(w http.ResponseWriter, id string, handle func(http.ResponseWriter, *job)) {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+		return
+	}
+	handle(w, j)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+/**
+ * An error recovered from a panicking simulation.
+ */
+type panicError struct {
+	value interface{}
+}
+
+func (pe panicError) Error() string {
+	return fmt.Sprint("simulation panicked: ", pe.value)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	bls "github.com/cfromknecht/bitcoin_load_spike"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testConfig = `{
+	"block_size": 1000000,
+	"num_blocks": 50,
+	"num_iterations": 2,
+	"seed": 3,
+	"spike_profile": {"spikes": [{"percent": 0, "load": 0.5}]}
+}`
+
+func request(t *testing.T, s *Server, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal("Invalid JSON response:", err, w.Body.String())
+	}
+}
+
+// Polls the job until it leaves the queued and running states
+func wait(t *testing.T, s *Server, id string) JobStatus {
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		var status JobStatus
+		decode(t, request(t, s, http.MethodGet, "/simulations/"+id, ""), &status)
+		if status.State != StateQueued && status.State != StateRunning {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Job", id, "did not finish")
+	return JobStatus{}
+}
+
+func TestSubmitAndFetchResults(t *testing.T) {
+	s := NewServer(1, 4)
+	defer s.Close()
+
+	w := request(t, s, http.MethodPost, "/simulations", testConfig)
+	if w.Code != http.StatusAccepted {
+		t.Fatal("Expected status", http.StatusAccepted, ", got", w.Code, w.Body.String())
+	}
+	var submitted JobStatus
+	decode(t, w, &submitted)
+	if submitted.ID != "1" || submitted.TotalIterations != 2 {
+		t.Error("Unexpected job status", submitted)
+	}
+
+	status := wait(t, s, submitted.ID)
+	if status.State != StateDone || status.Iterations != 2 || status.Finished == nil {
+		t.Fatal("Expected finished job, got", status)
+	}
+
+	w = request(t, s, http.MethodGet, "/simulations/1/results", "")
+	if w.Code != http.StatusOK {
+		t.Fatal("Expected status", http.StatusOK, ", got", w.Code, w.Body.String())
+	}
+	var results bls.Results
+	decode(t, w, &results)
+	if results.Iterations != 2 || len(results.Spikes) != 1 || results.Spikes[0].Confirmed == 0 {
+		t.Error("Unexpected results", results)
+	}
+
+	var statuses []JobStatus
+	decode(t, request(t, s, http.MethodGet, "/simulations", ""), &statuses)
+	if len(statuses) != 1 || statuses[0].ID != "1" {
+		t.Error("Expected one listed job, got", statuses)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	s := NewServer(1, 4)
+	defer s.Close()
+
+	// Occupy the worker so the second job stays queued
+	long := `{"num_blocks": 50, "num_iterations": 1000000}`
+	request(t, s, http.MethodPost, "/simulations", long)
+	request(t, s, http.MethodPost, "/simulations", testConfig)
+
+	w := request(t, s, http.MethodDelete, "/simulations/2", "")
+	if w.Code != http.StatusAccepted {
+		t.Fatal("Expected status", http.StatusAccepted, ", got", w.Code, w.Body.String())
+	}
+	var status JobStatus
+	decode(t, w, &status)
+	if status.State != StateCancelled {
+		t.Error("Expected cancelled job, got", status.State)
+	}
+
+	w = request(t, s, http.MethodGet, "/simulations/2/results", "")
+	if w.Code != http.StatusConflict {
+		t.Error("Expected status", http.StatusConflict, ", got", w.Code)
+	}
+	w = request(t, s, http.MethodDelete, "/simulations/2", "")
+	if w.Code != http.StatusConflict {
+		t.Error("Expected status", http.StatusConflict, ", got", w.Code)
+	}
+
+	request(t, s, http.MethodDelete, "/simulations/1", "")
+	if status := wait(t, s, "1"); status.State != StateCancelled {
+		t.Error("Expected cancelled job, got", status.State)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	s := NewServer(1, 0)
+	defer s.Close()
+
+	tests := []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodPost, "/simulations", `{"num_blocks": 0}`, http.StatusBadRequest},
+		{http.MethodPost, "/simulations", `{"num_blocks":`, http.StatusBadRequest},
+		{http.MethodGet, "/simulations/7", "", http.StatusNotFound},
+		{http.MethodGet, "/simulations/7/results", "", http.StatusNotFound},
+		{http.MethodGet, "/jobs", "", http.StatusNotFound},
+		{http.MethodPut, "/simulations", "", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		w := request(t, s, test.method, test.path, test.body)
+		if w.Code != test.code {
+			t.Error(test.method, test.path, "expected status", test.code, ", got", w.Code)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package bitcoin_load_spike
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 /**
  * `Spike`
@@ -8,8 +11,8 @@ import "fmt"
  * Defines the load at a given percentage
  */
 type Spike struct {
-	Percent float64
-	Load    float64
+	Percent float64 `json:"percent"`
+	Load    float64 `json:"load"`
 }
 
 /**
@@ -21,6 +24,31 @@ func (s Spike) String() string {
 	return fmt.Sprintf("%.4f:%.4f", s.Percent, s.Load)
 }
 
+/**
+ * Parses the string representation of a `Spike`, as used in the filenames
+ * of the logger outputs.
+ *
+ * @param s - "<percent>:<load>"
+ *
+ * @return - The `Spike` and any error parsing `s`
+ */
+func ParseSpike(s string) (spike Spike, err error) {
+	_, err = fmt.Sscanf(s, "%f:%f", &spike.Percent, &spike.Load)
+	if err != nil {
+		err = fmt.Errorf("invalid spike %q: %v", s, err)
+	}
+	return
+}
+
+/**
+ * Returns a human readable description of a `Spike`, used in chart legends
+ *
+ * @return - "<percent>%: load <load>"
+ */
+func (s Spike) Label() string {
+	return fmt.Sprintf("%.f%%: load %.2f", 100*s.Percent, s.Load)
+}
+
 /**
  * `SpikeProfile`
  *
@@ -39,7 +67,7 @@ func (s Spike) String() string {
  * 0% 20% 40%       100%
  */
 type SpikeProfile struct {
-	Spikes []Spike
+	Spikes []Spike `json:"spikes"`
 }
 
 /**
@@ -51,6 +79,23 @@ func (sp SpikeProfile) PrintProfile() {
 	}
 }
 
+/**
+ * Checks whether every `Spike` has the same load.
+ *
+ * @return - The load and whether it is constant
+ */
+func (sp SpikeProfile) constantLoad() (float64, bool) {
+	if len(sp.Spikes) == 0 {
+		return 0.0, false
+	}
+	for _, spike := range sp.Spikes {
+		if spike.Load != sp.Spikes[0].Load {
+			return 0.0, false
+		}
+	}
+	return sp.Spikes[0].Load, true
+}
+
 /**
  * Caclulates the current load given the percentage complete.
  *
@@ -90,23 +135,40 @@ func (sp SpikeProfile) currentSpikeIndex(percent float64) int {
  * Also checks that the percentages are ordered properly.
  */
 func (sp *SpikeProfile) valid() bool {
+	return sp.Validate() == nil
+}
+
+/**
+ * Verifies that a `SpikeProfile` is valid for use in the simulation and
+ * explains why it is not.
+ *
+ * @return - nil if the `SpikeProfile` is valid, otherwise the first problem
+ */
+func (sp *SpikeProfile) Validate() error {
+	if len(sp.Spikes) == 0 {
+		return errors.New("spike profile has no spikes")
+	}
+
 	previousTime := 0.0
 	for i, spike := range sp.Spikes {
 		// First spike must be at time 0.0
 		if i == 0 && spike.Percent != 0 {
-			return false
+			return fmt.Errorf("first spike must start at 0, got %f", spike.Percent)
 		}
 		// Check that all times and loads are valid
-		if !validPercent(spike.Percent) || !validLoad(spike.Load) {
-			return false
+		if !validPercent(spike.Percent) {
+			return fmt.Errorf("spike %d percent %f is not in [0, 1)", i, spike.Percent)
+		}
+		if !validLoad(spike.Load) {
+			return fmt.Errorf("spike %d load %f is negative", i, spike.Load)
 		}
 		// Check that the times are in order
 		if spike.Percent < previousTime {
-			return false
+			return fmt.Errorf("spike %d percent %f is before the previous spike", i, spike.Percent)
 		}
 		previousTime = spike.Percent
 	}
-	return true
+	return nil
 }
 
 /**
@@ -146,3 +146,18 @@ func TestCurrentIndex(t *testing.T) {
 		t.Error("Expected current load at 0.5 to be 2, got", sp.currentSpikeIndex(0.5))
 	}
 }
+
+func TestParseSpike(t *testing.T) {
+	spike := Spike{0.33, 10.0}
+	parsed, err := ParseSpike(spike.String())
+	if err != nil || parsed != spike {
+		t.Error("Expected", spike, ", got", parsed, err)
+	}
+	if spike.Label() != "33%: load 10.00" {
+		t.Error("Unexpected label", spike.Label())
+	}
+
+	if _, err := ParseSpike("0.33"); err == nil {
+		t.Error("Expected an error parsing a spike without a load")
+	}
+}
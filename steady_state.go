@@ -0,0 +1,114 @@
+package bitcoin_load_spike
+
+/**
+ * `steadyStateDetector`
+ *
+ * Records the `mempool` backlog after every block of a pilot iteration.  The
+ * backlog starts empty, so the initial blocks are biased towards short
+ * confirmation times.  The MSER rule picks the number of blocks to truncate.
+ */
+type steadyStateDetector struct {
+	backlog []float64
+}
+
+/**
+ * @return - The steady state detector writes no files
+ */
+func (ssd steadyStateDetector) FilePrefix() string {
+	return ""
+}
+
+/**
+ * @return - The steady state detector writes no files
+ */
+func (ssd steadyStateDetector) FileExtension() string {
+	return ""
+}
+
+/**
+ * Confirmed `txn`s are not needed to detect the steady state.
+ */
+func (ssd *steadyStateDetector) Log(blockTimestamp float64, t txn) {}
+
+/**
+ * Records the backlog after each block.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (ssd *steadyStateDetector) LogBlock(b block, pool *mempool) {
+	ssd.backlog = append(ssd.backlog, float64(pool.Len()))
+}
+
+/**
+ * @return - The steady state detector writes no files
+ */
+func (ssd *steadyStateDetector) Outputs() []string {
+	return []string{}
+}
+
+/**
+ * Clears the recorded backlog.
+ */
+func (ssd *steadyStateDetector) Reset() {
+	ssd.backlog = []float64{}
+}
+
+/**
+ * @return - The number of blocks to truncate according to MSER
+ */
+func (ssd *steadyStateDetector) truncation() int64 {
+	return int64(mser(ssd.backlog, MSER_BATCH_SIZE))
+}
+
+/**
+ * Applies the Marginal Standard Error Rule to `series`.  The series is
+ * averaged over batches of `batchSize`, and the truncation point minimizing
+ * the standard error of the remaining batches is chosen from the first half
+ * of the batches.
+ *
+ * @param series - The output series of a single run
+ * @param batchSize - The number of observations per batch, 5 for MSER-5
+ *
+ * @return - The number of observations of `series` to truncate
+ */
+func mser(series []float64, batchSize int) int {
+	numBatches := len(series) / batchSize
+	if numBatches < 2 {
+		return 0
+	}
+
+	batches := make([]float64, numBatches)
+	for i := range batches {
+		for _, y := range series[i*batchSize : (i+1)*batchSize] {
+			batches[i] += y
+		}
+		batches[i] /= float64(batchSize)
+	}
+
+	best := 0
+	bestStatistic := -1.0
+	for d := 0; d <= numBatches/2; d++ {
+		remaining := batches[d:]
+		n := float64(len(remaining))
+
+		mean := 0.0
+		for _, y := range remaining {
+			mean += y
+		}
+		mean /= n
+
+		statistic := 0.0
+		for _, y := range remaining {
+			statistic += (y - mean) * (y - mean)
+		}
+		statistic /= n * n
+
+		if bestStatistic < 0.0 || statistic < bestStatistic {
+			best = d
+			bestStatistic = statistic
+		}
+	}
+
+	return best * batchSize
+}
@@ -0,0 +1,90 @@
+package bitcoin_load_spike
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMSERStationary(t *testing.T) {
+	series := make([]float64, 100)
+	for i := range series {
+		series[i] = float64(i % 2)
+	}
+
+	if d := mser(series, MSER_BATCH_SIZE); d != 0 {
+		t.Error("Expected no truncation for a stationary series, got", d)
+	}
+}
+
+func TestMSERInitialTransient(t *testing.T) {
+	// Backlog grows for 20 observations, then fluctuates around 100
+	series := make([]float64, 200)
+	for i := range series {
+		if i < 20 {
+			series[i] = float64(5 * i)
+		} else {
+			series[i] = 100.0 + float64(i%3)
+		}
+	}
+
+	if d := mser(series, MSER_BATCH_SIZE); d != 20 {
+		t.Error("Expected truncation of 20 observations, got", d)
+	}
+}
+
+func TestMSERShortSeries(t *testing.T) {
+	if d := mser([]float64{1.0, 2.0, 3.0}, MSER_BATCH_SIZE); d != 0 {
+		t.Error("Expected no truncation for a short series, got", d)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	sp := &SpikeProfile{
+		[]Spike{
+			Spike{0.0, 0.1},
+		},
+	}
+
+	// A warm-up covering every block logs nothing
+	sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(1)).
+		UseSpikeProfile(sp).
+		UseWarmUp(10, 0.0).
+		AddCumulativeLogger("")
+
+	pendingTxnChan := make(chan txn)
+	readyChan := make(chan bool)
+	blockNumChan := make(chan int64)
+	go sim.createTxns(pendingTxnChan, readyChan, blockNumChan)
+	sim.createBlocks(pendingTxnChan, readyChan, blockNumChan)
+
+	cl := sim.loggers[0].(*CumulativeLogger)
+	if cl.plots[0].txnCount != 0 || cl.plots[0].censoredCount != 0 {
+		t.Error("Expected no txns to be logged during warm-up, got", cl.plots[0].txnCount, "confirmed and", cl.plots[0].censoredCount, "censored")
+	}
+}
+
+func TestSteadyStateCommonRandomNumbers(t *testing.T) {
+	sp := &SpikeProfile{[]Spike{Spike{0.0, 0.5}}}
+	intervals := func(steadyState bool) []float64 {
+		sim := NewLoadSpikeSimulation(DEFAULT_BLOCK_SIZE, int64(10), int64(2)).
+			UseSpikeProfile(sp).
+			UseSeed(7).
+			AddBlockStatsLogger("")
+		if steadyState {
+			sim.UseSteadyStateDetection()
+		}
+		sim.simulate(false)
+
+		totals := []float64{}
+		for _, stats := range sim.loggers[0].(*BlockStatsLogger).heights {
+			totals = append(totals, stats.totalInterval)
+		}
+		return totals
+	}
+
+	// The pilot iteration must not shift the seeds of the iterations
+	without, with := intervals(false), intervals(true)
+	if len(without) != 10 || !reflect.DeepEqual(without, with) {
+		t.Error("Expected the same block intervals with steady state detection, got", without, "and", with)
+	}
+}
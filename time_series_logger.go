@@ -2,83 +2,225 @@ package bitcoin_load_spike
 
 import (
 	"fmt"
-	_ "math"
+	"math"
+	"sort"
 )
 
-type timeSeriesPlot struct {
-	buckets        []float64
-	counts         []int64
-	smallestBucket int64
-	largestBucket  int64
-}
-
-func newTimeSeriesPlot() *timeSeriesPlot {
-	return &timeSeriesPlot{
-		buckets:        make([]float64, NUM_BUCKETS),
-		counts:         make([]int64, NUM_BUCKETS),
-		smallestBucket: NUM_BUCKETS,
-		largestBucket:  0,
-	}
-}
-
-func (tsp *timeSeriesPlot) updateBucket(i int64, age float64) {
-	total := tsp.buckets[i]*float64(tsp.counts[i]) + age
-	tsp.counts[i]++
-	tsp.buckets[i] = total / float64(tsp.counts[i])
-
-	if tsp.largestBucket < i {
-		tsp.largestBucket = i
-	}
-	if tsp.smallestBucket > i {
-		tsp.smallestBucket = i
-	}
+/**
+ * `TimeSeriesLogger`
+ *
+ * Groups `txn`s by their arrival time into buckets of `secsPerBucket` seconds
+ * and records the distribution of confirmation times within each bucket.
+ * Results are pooled across all iterations of the simulation, with one output
+ * per `TxnClass`.
+ *
+ * Each row of the output file has the format:
+ * `<bucket-number> | <bucket-start-time> | <txn-count> | <mean> | <median> | <p95>`
+ * where times are in seconds.  Buckets in which no `txn` arrived are omitted.
+ */
+type TimeSeriesLogger struct {
+	plots         []*timeSeriesPlot
+	secsPerBucket float64
+	filePrefix    string
 }
 
-func (tsp *timeSeriesPlot) output() (fileContents string) {
-	for i, avgTxnTime := range tsp.buckets[0 : NUM_BUCKETS-1] {
-		fileContents += fmt.Sprintf("%d | %f\n", i, avgTxnTime)
+/**
+ * Initializes a new `TimeSeriesLogger`
+ *
+ * @param prefix - The file prefix for writing the output files
+ * @param secsPerBucket - The width of each time bucket in seconds
+ * @param numClasses - The number of `TxnClass`es to record separately
+ *
+ * @return - An empty `TimeSeriesLogger`
+ */
+func newTimeSeriesLogger(prefix string, secsPerBucket float64, numClasses int) *TimeSeriesLogger {
+	tsl := &TimeSeriesLogger{
+		plots:         make([]*timeSeriesPlot, numClasses),
+		secsPerBucket: secsPerBucket,
+		filePrefix:    prefix,
 	}
-	return
-}
+	tsl.Reset()
 
-type TimeSeriesLogger struct {
-	plot          *timeSeriesPlot
-	secsPerBucket float64
-	filePrefix    string
+	return tsl
 }
 
+/**
+ * @return - The specified prefix for the output file.
+ */
 func (tsl TimeSeriesLogger) FilePrefix() string {
 	return tsl.filePrefix
 }
 
+/**
+ * @return - The file extension for `TimeSeriesLogger` output
+ */
 func (tsl TimeSeriesLogger) FileExtension() string {
 	return "tsl-dat"
 }
 
+/**
+ * Records the confirmation time of `txn` in the bucket of its arrival time.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
 func (tsl *TimeSeriesLogger) Log(blockTimestamp float64, t txn) {
 	age := blockTimestamp - t.time
-
 	b := int64(t.time / tsl.secsPerBucket)
-	// Extend buckets and counts if necessary
-	if b >= int64(len(tsl.plot.buckets)) {
-		diff := b - int64(len(tsl.plot.buckets)-1)
-		// Extend buckets
-		bucketsExtension := make([]float64, diff)
-		tsl.plot.buckets = append(tsl.plot.buckets, bucketsExtension...)
-		// Extend counts
-		countsExtension := make([]int64, diff)
-		tsl.plot.counts = append(tsl.plot.counts, countsExtension...)
-	}
 
-	tsl.plot.updateBucket(b, age)
+	tsl.plots[t.class].updateBucket(b, age)
 }
 
+/**
+ * Generates the time series file contents.
+ *
+ * @return - An output for each `TxnClass` containing every time bucket
+ */
 func (tsl *TimeSeriesLogger) Outputs() (outputs []string) {
 	fmt.Println("[TimeSeriesLogger]: generating time series plot")
-	outputs = append(outputs, tsl.plot.output())
+	for _, plot := range tsl.plots {
+		outputs = append(outputs, plot.output(tsl.secsPerBucket))
+	}
 	return
 }
 
+/**
+ * @return - Whether the outputs are per `TxnClass`, always true
+ */
+func (tsl *TimeSeriesLogger) ByClass() bool {
+	return true
+}
+
+/**
+ * Clears the logging state.
+ */
 func (tsl *TimeSeriesLogger) Reset() {
-	tsl.plot = newTimeSeriesPlot()
+	for i := range tsl.plots {
+		tsl.plots[i] = newTimeSeriesPlot()
+	}
+}
+
+/**
+ * Stores a `timeSeriesBucket` for each time interval, buckets are only
+ * allocated once a `txn` arrives in their interval.
+ */
+type timeSeriesPlot struct {
+	buckets []*timeSeriesBucket
+}
+
+/**
+ * Initializes a new `timeSeriesPlot`
+ *
+ * @return - An empty `timeSeriesPlot`
+ */
+func newTimeSeriesPlot() *timeSeriesPlot {
+	return &timeSeriesPlot{
+		buckets: []*timeSeriesBucket{},
+	}
+}
+
+/**
+ * Records `age` in bucket `i`, extending the buckets if necessary.
+ *
+ * @param i - The time bucket index
+ * @param age - The confirmation time of the `txn`
+ */
+func (tsp *timeSeriesPlot) updateBucket(i int64, age float64) {
+	if i >= int64(len(tsp.buckets)) {
+		extension := make([]*timeSeriesBucket, i-int64(len(tsp.buckets))+1)
+		tsp.buckets = append(tsp.buckets, extension...)
+	}
+	if tsp.buckets[i] == nil {
+		tsp.buckets[i] = newTimeSeriesBucket()
+	}
+
+	tsp.buckets[i].add(age)
+}
+
+/**
+ * Returns a string representation of the plot to be written to a file.
+ *
+ * @param secsPerBucket - The width of each bucket in seconds
+ *
+ * @return - The file contents for the time series
+ */
+func (tsp *timeSeriesPlot) output(secsPerBucket float64) (fileContents string) {
+	for i, bucket := range tsp.buckets {
+		if bucket == nil {
+			continue
+		}
+
+		fileContents += fmt.Sprintf("%d | %f | %d | %f | %f | %f\n",
+			i,
+			float64(i)*secsPerBucket,
+			bucket.txnCount,
+			bucket.totalAge/float64(bucket.txnCount),
+			bucket.quantile(0.5),
+			bucket.quantile(0.95))
+	}
+	return
+}
+
+/**
+ * Records the confirmation times of `txn`s arriving within a single time
+ * bucket.  Confirmation times are kept in a sparse log scale histogram with
+ * `TIME_SERIES_BUCKETS_PER_ORDER` buckets per order of magnitude.
+ */
+type timeSeriesBucket struct {
+	txnCount int64
+	totalAge float64
+	ages     map[int64]int64
+}
+
+/**
+ * Initializes a new `timeSeriesBucket`
+ *
+ * @return - An empty `timeSeriesBucket`
+ */
+func newTimeSeriesBucket() *timeSeriesBucket {
+	return &timeSeriesBucket{
+		ages: make(map[int64]int64),
+	}
+}
+
+/**
+ * Adds a confirmation time to the bucket.
+ *
+ * @param age - The confirmation time of the `txn`
+ */
+func (tsb *timeSeriesBucket) add(age float64) {
+	b := int64(math.Ceil(float64(TIME_SERIES_BUCKETS_PER_ORDER) * math.Log10(age)))
+	if b < -NEGATIVE_ORDERS*TIME_SERIES_BUCKETS_PER_ORDER {
+		b = -NEGATIVE_ORDERS * TIME_SERIES_BUCKETS_PER_ORDER
+	}
+
+	tsb.ages[b]++
+	tsb.txnCount++
+	tsb.totalAge += age
+}
+
+/**
+ * Calculates the confirmation time below which the fraction `q` of the
+ * bucket's `txn`s fall.
+ *
+ * @param q - The desired quantile in [0, 1]
+ *
+ * @return - The confirmation time (in seconds) of the `q` quantile
+ */
+func (tsb *timeSeriesBucket) quantile(q float64) float64 {
+	keys := make([]int64, 0, len(tsb.ages))
+	for k := range tsb.ages {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	threshold := q * float64(tsb.txnCount)
+	cumulativeTotal := float64(0.0)
+	for _, k := range keys {
+		cumulativeTotal += float64(tsb.ages[k])
+		if cumulativeTotal >= threshold {
+			return math.Pow(10.0, float64(k)/float64(TIME_SERIES_BUCKETS_PER_ORDER))
+		}
+	}
+	return 0.0
 }
@@ -0,0 +1,54 @@
+package bitcoin_load_spike
+
+import "testing"
+
+func TestTimeSeriesFileExtension(t *testing.T) {
+	expectedExtension := "tsl-dat"
+
+	tsl := newTimeSeriesLogger("", DEFAULT_SECS_PER_BUCKET, 1)
+
+	if tsl.FileExtension() != expectedExtension {
+		t.Error("Expected file extension", expectedExtension, ", got", tsl.FileExtension())
+	}
+}
+
+func TestTimeSeriesLog(t *testing.T) {
+	tsl := newTimeSeriesLogger("", 100.0, 1)
+
+	tsl.Log(110.0, newTestTxn(100.0, 0))
+	tsl.Log(350.0, newTestTxn(250.0, 0))
+	tsl.Log(1250.0, newTestTxn(250.0, 0))
+
+	if len(tsl.plots[0].buckets) != 3 {
+		t.Fatal("Expected 3 buckets, got", len(tsl.plots[0].buckets))
+	}
+	if tsl.plots[0].buckets[0] != nil {
+		t.Error("Expected bucket 0 to be untouched")
+	}
+	if tsl.plots[0].buckets[1].txnCount != 1 {
+		t.Error("Expected 1 txn in bucket 1, got", tsl.plots[0].buckets[1].txnCount)
+	}
+	if tsl.plots[0].buckets[2].txnCount != 2 {
+		t.Error("Expected 2 txns in bucket 2, got", tsl.plots[0].buckets[2].txnCount)
+	}
+}
+
+func TestTimeSeriesOutput(t *testing.T) {
+	expectedOutput := "1 | 100.000000 | 1 | 10.000000 | 10.000000 | 10.000000\n" +
+		"2 | 200.000000 | 2 | 550.000000 | 100.000000 | 1000.000000\n"
+
+	tsl := newTimeSeriesLogger("", 100.0, 1)
+	tsl.Log(110.0, newTestTxn(100.0, 0))
+	tsl.Log(350.0, newTestTxn(250.0, 0))
+	tsl.Log(1250.0, newTestTxn(250.0, 0))
+
+	output := tsl.Outputs()[0]
+	if output != expectedOutput {
+		t.Error("Expected output '", expectedOutput, "', got '", output, "'")
+	}
+
+	tsl.Reset()
+	if len(tsl.plots[0].buckets) != 0 {
+		t.Error("Expected reset to clear buckets, got", len(tsl.plots[0].buckets))
+	}
+}
@@ -0,0 +1,193 @@
+package bitcoin_load_spike
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+)
+
+/**
+ * `TraceLogger`
+ *
+ * Streams a sample of individual `txn`s to a CSV file for offline analysis.
+ * Each `txn` is kept with probability `sampleRate`, sampling is independent of
+ * the simulation's randomness but derived from its seed, so runs with the same
+ * seed write the same trace.  `txn`s still unconfirmed at the end of an
+ * iteration are written with empty block fields.
+ *
+ * Each row of the output file has the format:
+ * `<iteration>,<arrival-time>,<spike-index>,<block-height>,<block-timestamp>,<class>,<size>,<fee-rate>`
+ * where the spike index is within the `TxnClass` and the class is empty for
+ * the default class.
+ */
+type TraceLogger struct {
+	path       string
+	sampleRate float64
+	spikes     []classSpike
+	seed       func() int64
+	rng        *rand.Rand
+	file       *os.File
+	writer     *bufio.Writer
+	pending    []txn
+	iteration  int64
+}
+
+/**
+ * Initializes a new `TraceLogger`.  The file is created on the first write.
+ *
+ * @param path - The path of the trace file
+ * @param sampleRate - The fraction of `txn`s to record, in (0, 1]
+ * @param spikes - The spikes of every `TxnClass` of the simulation
+ * @param seed - Returns the seed of the running simulation
+ *
+ * @return - The new `TraceLogger`
+ */
+func newTraceLogger(path string, sampleRate float64, spikes []classSpike, seed func() int64) *TraceLogger {
+	return &TraceLogger{
+		path:       path,
+		sampleRate: sampleRate,
+		spikes:     spikes,
+		seed:       seed,
+		pending:    []txn{},
+	}
+}
+
+/**
+ * @return - The path of the trace file.
+ */
+func (tl TraceLogger) FilePrefix() string {
+	return tl.path
+}
+
+/**
+ * @return - The file extension for `TraceLogger` output
+ */
+func (tl TraceLogger) FileExtension() string {
+	return "csv"
+}
+
+/**
+ * Samples a confirmed `txn`, it is written once its block is known.
+ *
+ * @param blockTimestamp - The timestamp of the block that recorded `txn`
+ * @param t - The `txn` that was recorded
+ */
+func (tl *TraceLogger) Log(blockTimestamp float64, t txn) {
+	if tl.sample() {
+		tl.pending = append(tl.pending, t)
+	}
+}
+
+/**
+ * Writes the sampled `txn`s confirmed by the block.
+ *
+ * @param b - The mined block
+ * @param pool - The `mempool` after the block's `txn`s were removed
+ */
+func (tl *TraceLogger) LogBlock(b block, pool *mempool) {
+	for _, t := range tl.pending {
+		tl.write(fmt.Sprintf("%d,%f,%d,%d,%f,%s\n", tl.iteration, t.time, tl.spikes[t.index].spike, b.height, b.timestamp, tl.txnFields(t)))
+	}
+	tl.pending = tl.pending[:0]
+}
+
+/**
+ * Writes a sample of the unconfirmed `txn`s and advances the iteration.
+ *
+ * @param endTimestamp - Timestamp of the last block of the iteration
+ * @param pool - The `txn`s that remain unconfirmed
+ */
+func (tl *TraceLogger) EndIteration(endTimestamp float64, pool *mempool) {
+	for _, t := range pool.txns {
+		if tl.sample() {
+			tl.write(fmt.Sprintf("%d,%f,%d,,,%s\n", tl.iteration, t.time, tl.spikes[t.index].spike, tl.txnFields(t)))
+		}
+	}
+	tl.iteration++
+	tl.rng = nil
+}
+
+/**
+ * Flushes and closes the trace file.  The trace is written while the
+ * simulation runs, so there are no outputs to return.
+ *
+ * @return - No outputs
+ */
+func (tl *TraceLogger) Outputs() (outputs []string) {
+	if tl.file != nil {
+		fmt.Println("[TraceLogger]: wrote txn trace to", tl.path)
+		tl.close()
+	}
+	return
+}
+
+/**
+ * Clears the logging state, flushing and closing the trace file if the run
+ * did not.  A subsequent run overwrites the trace file.
+ */
+func (tl *TraceLogger) Reset() {
+	tl.close()
+	tl.pending = tl.pending[:0]
+	tl.iteration = 0
+	tl.rng = nil
+}
+
+/**
+ * Flushes and closes the trace file, if it is open.
+ */
+func (tl *TraceLogger) close() {
+	if tl.file == nil {
+		return
+	}
+
+	check(tl.writer.Flush())
+	check(tl.file.Close())
+	tl.file = nil
+	tl.writer = nil
+}
+
+/**
+ * @return - The class, size and fee rate fields of `t`
+ */
+func (tl *TraceLogger) txnFields(t txn) string {
+	return fmt.Sprintf("%s,%f,%f", tl.spikes[t.index].name, t.size, t.feeRate)
+}
+
+/**
+ * Draws whether the next `txn` should be recorded.  Each iteration samples
+ * from its own seed, derived from the simulation's seed.
+ *
+ * @return - Whether the next `txn` should be recorded
+ */
+func (tl *TraceLogger) sample() bool {
+	if tl.sampleRate >= 1.0 {
+		return true
+	}
+	if tl.rng == nil {
+		seed := iterationSeed(tl.seed(), tl.iteration) ^ TRACE_SEED_SALT
+		tl.rng = rand.New(rand.NewSource(seed))
+	}
+	return tl.rng.Float64() < tl.sampleRate
+}
+
+/**
+ * Writes a row to the trace file, creating it and writing the header if
+ * necessary.
+ *
+ * @param row - The row to write
+ */
+func (tl *TraceLogger) write(row string) {
+	if tl.file == nil {
+		file, err := os.Create(tl.path)
+		check(err)
+
+		tl.file = file
+		tl.writer = bufio.NewWriter(file)
+		_, err = tl.writer.WriteString("iteration,arrival_time,spike_index,block_height,block_timestamp,class,size,fee_rate\n")
+		check(err)
+	}
+
+	_, err := tl.writer.WriteString(row)
+	check(err)
+}
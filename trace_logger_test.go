@@ -0,0 +1,105 @@
+package bitcoin_load_spike
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testSeed() int64 {
+	return 1
+}
+
+func TestTraceLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	sp := &SpikeProfile{[]Spike{Spike{0.0, 0.1}, Spike{0.5, 0.2}}}
+	tl := newTraceLogger(path, 1.0, classSpikes([]TxnClass{defaultTxnClass(sp)}), testSeed)
+
+	pool := newMempool()
+	pool.add(newTestTxn(30.0, 1))
+
+	tl.Log(100.0, newTestTxn(10.0, 0))
+	tl.Log(100.0, newTestTxn(20.0, 1))
+	tl.LogBlock(block{height: 3, timestamp: 100.0}, pool)
+	tl.EndIteration(100.0, pool)
+
+	if outputs := tl.Outputs(); len(outputs) != 0 {
+		t.Error("Expected no outputs, got", len(outputs))
+	}
+
+	expectedTrace := "iteration,arrival_time,spike_index,block_height,block_timestamp,class,size,fee_rate\n" +
+		"0,10.000000,0,3,100.000000,,873.000000,1.000000\n" +
+		"0,20.000000,1,3,100.000000,,873.000000,1.000000\n" +
+		"0,30.000000,1,,,,873.000000,1.000000\n"
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("Expected trace file to be written, got", err)
+	}
+	if string(contents) != expectedTrace {
+		t.Error("Expected trace '", expectedTrace, "', got '", string(contents), "'")
+	}
+}
+
+func TestTraceLoggerReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	sp := &SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	tl := newTraceLogger(path, 1.0, classSpikes([]TxnClass{defaultTxnClass(sp)}), testSeed)
+
+	tl.Log(100.0, newTestTxn(10.0, 0))
+	tl.LogBlock(block{height: 3, timestamp: 100.0}, newMempool())
+	tl.EndIteration(100.0, newMempool())
+	// Simulations run without `Run` only reset their loggers
+	tl.Reset()
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("Expected trace file to be written, got", err)
+	}
+	expectedTrace := "iteration,arrival_time,spike_index,block_height,block_timestamp,class,size,fee_rate\n" +
+		"0,10.000000,0,3,100.000000,,873.000000,1.000000\n"
+	if string(contents) != expectedTrace {
+		t.Error("Expected trace '", expectedTrace, "', got '", string(contents), "'")
+	}
+}
+
+func TestTraceLoggerSeed(t *testing.T) {
+	draw := func(tl *TraceLogger) []bool {
+		samples := []bool{}
+		for i := 0; i < 100; i++ {
+			samples = append(samples, tl.sample())
+		}
+		return samples
+	}
+
+	first := draw(newTraceLogger("", 0.5, nil, testSeed))
+	second := newTraceLogger("", 0.5, nil, testSeed)
+	if !reflect.DeepEqual(first, draw(second)) {
+		t.Error("Expected the same samples from the same seed")
+	}
+
+	second.EndIteration(0.0, newMempool())
+	if reflect.DeepEqual(first, draw(second)) {
+		t.Error("Expected different samples in the next iteration")
+	}
+	second.Reset()
+	if !reflect.DeepEqual(first, draw(second)) {
+		t.Error("Expected the same samples after a reset")
+	}
+}
+
+func TestTraceLoggerSampling(t *testing.T) {
+	tl := newTraceLogger("", 0.1, nil, testSeed)
+
+	sampled := 0
+	for i := 0; i < 100000; i++ {
+		if tl.sample() {
+			sampled++
+		}
+	}
+
+	if sampled < 9000 || sampled > 11000 {
+		t.Error("Expected roughly 10000 sampled txns, got", sampled)
+	}
+}
@@ -0,0 +1,271 @@
+package bitcoin_load_spike
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+)
+
+// Fee rate of the default transaction class, in satoshis per byte
+const DEFAULT_FEE_RATE float64 = 1.0
+
+// Class names appear in output filenames, trace rows and metric labels
+var TXN_CLASS_NAME = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+/**
+ * `TxnClass`
+ *
+ * A named kind of transaction, e.g. exchange withdrawals or payments, with
+ * its own demand.  Each class arrives as an independent poisson process
+ * following its `SpikeProfile`, where the load is a percentage of
+ * `BITCOIN_MAX_TPS` in `txn`s.  `Size` is in bytes and `FeeRate` in
+ * satoshis per byte.  Blocks include the highest fee rates first, so classes
+ * with the same fee rate are confirmed in order of arrival.  A class with a
+ * `FeeTarget` instead pays the rate suggested by the simulation's
+ * `FeeEstimator` for confirming within that many blocks, drawing from
+ * `FeeRate` only while there is no estimate.
+ *
+ * Sizes are drawn from `Size` truncated at the simulation's block size: a
+ * size larger than a block is redrawn, so every `txn` can be confirmed.  The
+ * median size must fit in a block, so that most draws are kept.
+ */
+type TxnClass struct {
+	Name         string       `json:"name"`
+	SpikeProfile SpikeProfile `json:"spike_profile"`
+	Size         Distribution `json:"size"`
+	FeeRate      Distribution `json:"fee_rate"`
+	Batching     *Batching    `json:"batching,omitempty"`
+	FeeTarget    int          `json:"fee_target,omitempty"`
+	Bidding      *Bidding     `json:"bidding,omitempty"`
+}
+
+/**
+ * `Batching`
+ *
+ * Makes a `TxnClass` behave like an exchange or payment processor that
+ * batches its outgoing payments.  The arrivals of the class are then
+ * payments: a batch opens with the first payment and is broadcast as a single
+ * `txn` `Interval` seconds later, or as soon as it holds `MaxPayments`
+ * payments, 0 for no limit.  The class's `Size` is the size of a `txn` making
+ * one payment, every further payment in the batch adds an output of
+ * `OutputSize` bytes.  A batch is also broadcast as soon as another output
+ * would not fit in a block.
+ */
+type Batching struct {
+	Interval    float64 `json:"interval"`
+	MaxPayments int     `json:"max_payments"`
+	OutputSize  float64 `json:"output_size"`
+}
+
+/**
+ * `Bidding`
+ *
+ * Makes the demand of a `TxnClass` respond to congestion.  After every block
+ * users observe the fee rate of the pending `txn` at a depth of
+ * `TargetBlocks` full blocks, the rate needed to confirm within that many
+ * blocks if no other `txn`s arrived.  Each arriving `txn` bids that rate
+ * plus a `Premium` fraction, or its class's `FeeRate` if that is higher.
+ * Users that would have to bid more than their `MaxFeeRate` do not transact.
+ */
+type Bidding struct {
+	TargetBlocks float64      `json:"target_blocks"`
+	Premium      float64      `json:"premium"`
+	MaxFeeRate   Distribution `json:"max_fee_rate"`
+}
+
+/**
+ * Builds the unnamed class used when a simulation has a single
+ * `SpikeProfile`: every `txn` has `BITCOIN_TRANSACTION_SIZE` bytes and pays
+ * `DEFAULT_FEE_RATE`.
+ *
+ * @param sp - The `SpikeProfile` of the class
+ *
+ * @return - The default `TxnClass`
+ */
+func defaultTxnClass(sp *SpikeProfile) TxnClass {
+	return TxnClass{
+		SpikeProfile: *sp,
+		Size:         ConstantDistribution(BITCOIN_TRANSACTION_SIZE),
+		FeeRate:      ConstantDistribution(DEFAULT_FEE_RATE),
+	}
+}
+
+/**
+ * Verifies that the `TxnClass` is valid for use in the simulation.
+ *
+ * @return - nil if the `TxnClass` is valid, otherwise the first problem
+ */
+func (tc *TxnClass) Validate() error {
+	if err := tc.SpikeProfile.Validate(); err != nil {
+		return fmt.Errorf("class %q: %v", tc.Name, err)
+	}
+	if err := tc.Size.Validate(); err != nil {
+		return fmt.Errorf("class %q size: %v", tc.Name, err)
+	}
+	if !tc.Size.positive() {
+		return fmt.Errorf("class %q size must be positive", tc.Name)
+	}
+	if err := tc.FeeRate.Validate(); err != nil {
+		return fmt.Errorf("class %q fee rate: %v", tc.Name, err)
+	}
+	if tc.FeeTarget < 0 {
+		return fmt.Errorf("class %q fee target %d is negative", tc.Name, tc.FeeTarget)
+	}
+	if b := tc.Bidding; b != nil {
+		if tc.FeeTarget > 0 {
+			return fmt.Errorf("class %q cannot both bid and use a fee target", tc.Name)
+		}
+		if b.TargetBlocks <= 0.0 || b.Premium < 0.0 {
+			return fmt.Errorf("class %q bidding requires positive target blocks and a non-negative premium", tc.Name)
+		}
+		if err := b.MaxFeeRate.Validate(); err != nil {
+			return fmt.Errorf("class %q maximum fee rate: %v", tc.Name, err)
+		}
+	}
+	if b := tc.Batching; b != nil {
+		if b.Interval <= 0.0 || b.MaxPayments < 0 || b.OutputSize < 0.0 {
+			return fmt.Errorf("class %q batching requires a positive interval and non-negative sizes", tc.Name)
+		}
+	}
+	return nil
+}
+
+/**
+ * Verifies that the `txn`s of the class fit in blocks of `blockSize` bytes:
+ * the median size, and a full batch if the sizes of batches are bounded.
+ *
+ * @param blockSize - The maximum block size in bytes
+ *
+ * @return - nil if the `txn`s fit, otherwise the problem
+ */
+func (tc *TxnClass) fits(blockSize float64) error {
+	if tc.Size.MedianValue() > blockSize {
+		return fmt.Errorf("block size %f cannot fit most txns of class %q", blockSize, tc.Name)
+	}
+	if size, ok := tc.maxBatchSize(); ok && size > blockSize {
+		return fmt.Errorf("block size %f cannot fit a full batch of class %q", blockSize, tc.Name)
+	}
+	return nil
+}
+
+/**
+ * Returns the largest `txn` the class can draw in a simulation of
+ * `blockSize` bytes, where larger sizes are redrawn and batches are
+ * broadcast before outgrowing a block.
+ *
+ * @param blockSize - The block size of the simulation in bytes
+ *
+ * @return - The size in bytes of the largest `txn` or batch
+ */
+func (tc *TxnClass) largestSize(blockSize float64) float64 {
+	size := tc.Size.MaxValue()
+	if b := tc.Batching; b != nil {
+		if b.MaxPayments == 0 {
+			return blockSize
+		}
+		size += float64(b.MaxPayments-1) * b.OutputSize
+	}
+	return math.Min(size, blockSize)
+}
+
+/**
+ * Returns the largest size of a batch whose sizes are bounded.
+ *
+ * @return - The size in bytes of a batch of `MaxPayments` payments, or false
+ *           if the class does not batch, the batches are unbounded or the
+ *           `Size` is not constant
+ */
+func (tc *TxnClass) maxBatchSize() (float64, bool) {
+	b := tc.Batching
+	if b == nil || b.MaxPayments == 0 || tc.Size.Kind != DISTRIBUTION_CONSTANT {
+		return 0.0, false
+	}
+	return tc.Size.Value + float64(b.MaxPayments-1)*b.OutputSize, true
+}
+
+/**
+ * Verifies that `classes` can be simulated together: each class is valid
+ * and has an alphanumeric name, and no two classes share a name.
+ *
+ * @param classes - The `TxnClass`es of a simulation
+ *
+ * @return - nil if the classes are valid, otherwise the first problem
+ */
+func ValidateTxnClasses(classes []TxnClass) error {
+	if len(classes) == 0 {
+		return errors.New("no transaction classes")
+	}
+
+	names := map[string]bool{}
+	for i := range classes {
+		if err := classes[i].Validate(); err != nil {
+			return err
+		}
+		name := classes[i].Name
+		if !TXN_CLASS_NAME.MatchString(name) {
+			return fmt.Errorf("transaction class %d name %q is not alphanumeric", i, name)
+		}
+		if names[name] {
+			return fmt.Errorf("transaction class %q is defined twice", name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+/**
+ * @param classes - The `TxnClass`es of a simulation
+ *
+ * @return - Whether any class asks a `FeeEstimator` for its fee rates
+ */
+func useFeeEstimates(classes []TxnClass) bool {
+	for _, class := range classes {
+		if class.FeeTarget > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * `classSpike`
+ *
+ * A spike of a `TxnClass`.  The spikes of every class are numbered
+ * consecutively, and `txn.index` refers to this numbering, so loggers record
+ * each spike of each class separately.
+ */
+type classSpike struct {
+	Spike
+	class int
+	name  string
+	spike int
+}
+
+/**
+ * Returns the name used in output filenames
+ *
+ * @return - "<spike>" for the default class, otherwise "<class>-<spike>"
+ */
+func (cs classSpike) String() string {
+	if cs.name == "" {
+		return cs.Spike.String()
+	}
+	return cs.name + "-" + cs.Spike.String()
+}
+
+/**
+ * Numbers the spikes of every class consecutively.
+ *
+ * @param classes - The `TxnClass`es of a simulation
+ *
+ * @return - The spikes of every class, in order
+ */
+func classSpikes(classes []TxnClass) (spikes []classSpike) {
+	for i, class := range classes {
+		for j, spike := range class.SpikeProfile.Spikes {
+			spikes = append(spikes, classSpike{spike, i, class.Name, j})
+		}
+	}
+	return
+}
@@ -0,0 +1,140 @@
+package bitcoin_load_spike
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestValidateTxnClasses(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	valid := TxnClass{Name: "payments", SpikeProfile: sp, Size: ConstantDistribution(250.0), FeeRate: ConstantDistribution(5.0)}
+	if err := ValidateTxnClasses([]TxnClass{valid}); err != nil {
+		t.Error("Expected class to be valid, got", err)
+	}
+
+	invalid := map[string][]TxnClass{
+		"no classes":    nil,
+		"unnamed class": []TxnClass{defaultTxnClass(&sp)},
+		"duplicate":     []TxnClass{valid, valid},
+		"unsafe name":   []TxnClass{TxnClass{Name: "a/b", SpikeProfile: sp, Size: valid.Size, FeeRate: valid.FeeRate}},
+		"zero size":     []TxnClass{TxnClass{Name: "zero", SpikeProfile: sp, Size: ConstantDistribution(0.0), FeeRate: valid.FeeRate}},
+		"unknown kind":  []TxnClass{TxnClass{Name: "kind", SpikeProfile: sp, Size: valid.Size, FeeRate: Distribution{Kind: "pareto"}}},
+		"bad profile":   []TxnClass{TxnClass{Name: "late", SpikeProfile: SpikeProfile{[]Spike{Spike{0.5, 0.1}}}, Size: valid.Size, FeeRate: valid.FeeRate}},
+		"no interval":   []TxnClass{TxnClass{Name: "batch", SpikeProfile: sp, Size: valid.Size, FeeRate: valid.FeeRate, Batching: &Batching{MaxPayments: 10}}},
+		"no bid target": []TxnClass{TxnClass{Name: "bid", SpikeProfile: sp, Size: valid.Size, FeeRate: valid.FeeRate, Bidding: &Bidding{MaxFeeRate: valid.FeeRate}}},
+		"bid and fee target": []TxnClass{TxnClass{Name: "bid", SpikeProfile: sp, Size: valid.Size, FeeRate: valid.FeeRate, FeeTarget: 2,
+			Bidding: &Bidding{TargetBlocks: 1.0, MaxFeeRate: valid.FeeRate}}},
+	}
+	for name, classes := range invalid {
+		if ValidateTxnClasses(classes) == nil {
+			t.Error("Expected", name, "to be invalid")
+		}
+	}
+}
+
+func TestTxnClassFits(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	fits := map[string]TxnClass{
+		"exponential tail": TxnClass{Name: "a", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_EXPONENTIAL, Mean: 1000.0}},
+		"unbounded batch": TxnClass{Name: "b", SpikeProfile: sp, Size: ConstantDistribution(250.0),
+			Batching: &Batching{Interval: 600.0, OutputSize: 34.0}},
+	}
+	for name, class := range fits {
+		if err := class.fits(1000.0); err != nil {
+			t.Error("Expected", name, "to fit, got", err)
+		}
+	}
+
+	tooLarge := map[string]TxnClass{
+		"large median": TxnClass{Name: "c", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_LOGNORMAL, Median: 1500.0, Sigma: 1.0}},
+		"wide uniform": TxnClass{Name: "d", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_UNIFORM, Min: 900.0, Max: 1200.0}},
+		"full batch": TxnClass{Name: "e", SpikeProfile: sp, Size: ConstantDistribution(250.0),
+			Batching: &Batching{Interval: 600.0, MaxPayments: 100, OutputSize: 34.0}},
+	}
+	for name, class := range tooLarge {
+		if class.fits(1000.0) == nil {
+			t.Error("Expected", name, "not to fit")
+		}
+	}
+}
+
+func TestTxnClassLargestSize(t *testing.T) {
+	sp := SpikeProfile{[]Spike{Spike{0.0, 0.1}}}
+	expected := map[float64]TxnClass{
+		250.0: TxnClass{Name: "a", SpikeProfile: sp, Size: ConstantDistribution(250.0)},
+		600.0: TxnClass{Name: "b", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_UNIFORM, Min: 200.0, Max: 600.0}},
+		// Larger sizes are redrawn
+		1000.0: TxnClass{Name: "c", SpikeProfile: sp, Size: Distribution{Kind: DISTRIBUTION_EXPONENTIAL, Mean: 300.0}},
+		250.0 + 4*34.0: TxnClass{Name: "d", SpikeProfile: sp, Size: ConstantDistribution(250.0),
+			Batching: &Batching{Interval: 600.0, MaxPayments: 5, OutputSize: 34.0}},
+	}
+	for size, class := range expected {
+		if largest := class.largestSize(1000.0); largest != size {
+			t.Error("Expected the largest txn of class", class.Name, "to have", size, "bytes, got", largest)
+		}
+	}
+
+	// Unbounded batches grow until another output would not fit
+	unbounded := TxnClass{Name: "e", SpikeProfile: sp, Size: ConstantDistribution(250.0),
+		Batching: &Batching{Interval: 600.0, OutputSize: 34.0}}
+	if largest := unbounded.largestSize(1000.0); largest != 1000.0 {
+		t.Error("Expected unbounded batches up to the block size, got", largest)
+	}
+}
+
+func TestClassSpikes(t *testing.T) {
+	classes := []TxnClass{
+		TxnClass{Name: "a", SpikeProfile: SpikeProfile{[]Spike{Spike{0.0, 0.1}, Spike{0.5, 0.2}}}},
+		TxnClass{Name: "b", SpikeProfile: SpikeProfile{[]Spike{Spike{0.0, 0.3}}}},
+	}
+
+	spikes := classSpikes(classes)
+	if len(spikes) != 3 || spikes[2].class != 1 || spikes[2].spike != 0 || spikes[1].spike != 1 {
+		t.Fatal("Expected spikes numbered across classes, got", spikes)
+	}
+	if spikes[2].String() != "b-"+spikes[2].Spike.String() {
+		t.Error("Expected class name in spike name, got", spikes[2].String())
+	}
+
+	unnamed := classSpikes([]TxnClass{defaultTxnClass(&classes[0].SpikeProfile)})
+	if unnamed[1].String() != classes[0].SpikeProfile.Spikes[1].String() {
+		t.Error("Expected default class spike names to be unchanged, got", unnamed[1].String())
+	}
+}
+
+func TestDistributionSample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	distributions := []Distribution{
+		ConstantDistribution(250.0),
+		Distribution{Kind: DISTRIBUTION_UNIFORM, Min: 200.0, Max: 300.0},
+		Distribution{Kind: DISTRIBUTION_EXPONENTIAL, Mean: 250.0},
+		Distribution{Kind: DISTRIBUTION_LOGNORMAL, Median: 200.0, Sigma: 0.5},
+	}
+
+	for _, d := range distributions {
+		if err := d.Validate(); err != nil {
+			t.Error("Expected", d.Kind, "distribution to be valid, got", err)
+		}
+
+		total := 0.0
+		n := 100000
+		for i := 0; i < n; i++ {
+			v := d.Sample(r)
+			if v < 0.0 {
+				t.Fatal("Expected non-negative", d.Kind, "sample, got", v)
+			}
+			total += v
+		}
+		mean := total / float64(n)
+		if mean < 0.98*d.MeanValue() || mean > 1.02*d.MeanValue() {
+			t.Error("Expected", d.Kind, "sample mean near", d.MeanValue(), ", got", mean)
+		}
+	}
+
+	// Constant distributions leave the random stream unchanged
+	a, b := rand.New(rand.NewSource(2)), rand.New(rand.NewSource(2))
+	ConstantDistribution(1.0).Sample(a)
+	if a.Float64() != b.Float64() {
+		t.Error("Expected constant distribution not to draw from the source")
+	}
+}
@@ -0,0 +1,199 @@
+package bitcoin_load_spike
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// The dataset of the Bitcoin Traffic Bulletin's CDF points, relative to the
+// repository
+const TRAFFIC_BULLETIN_REFERENCE = "reference/traffic_bulletin.json"
+
+/**
+ * `ReferenceDataset`
+ *
+ * Confirmation time CDFs that the simulation should reproduce, stored as
+ * JSON.  Every scenario records the `Source` of its points.  A `Bulletin`
+ * dataset transcribes the Bitcoin Traffic Bulletin, and each of its scenarios
+ * must also cite the `Figure` and `Date` its points were read from.  A
+ * scenario fails validation when the KS distance between its simulated and
+ * reference CDFs exceeds `MaxKSDistance`.
+ */
+type ReferenceDataset struct {
+	Source        string              `json:"source"`
+	Bulletin      bool                `json:"bulletin,omitempty"`
+	MaxKSDistance float64             `json:"max_ks_distance"`
+	Scenarios     []ReferenceScenario `json:"scenarios"`
+}
+
+/**
+ * `ReferenceScenario`
+ *
+ * A constant load simulation and the reference CDF of its confirmation
+ * times, given as `(time, probability)` points.
+ */
+type ReferenceScenario struct {
+	Name          string           `json:"name"`
+	Source        string           `json:"source"`
+	Figure        string           `json:"figure,omitempty"`
+	Date          string           `json:"date,omitempty"`
+	BlockSize     float64          `json:"block_size"`
+	Load          float64          `json:"load"`
+	NumBlocks     int64            `json:"num_blocks"`
+	NumIterations int64            `json:"num_iterations"`
+	Seed          int64            `json:"seed"`
+	Points        []ReferencePoint `json:"points"`
+}
+
+/**
+ * A point of a reference CDF, the probability of confirming within `Time`
+ * seconds.
+ */
+type ReferencePoint struct {
+	Time        float64 `json:"time"`
+	Probability float64 `json:"probability"`
+}
+
+/**
+ * The outcome of validating a single `ReferenceScenario`.
+ */
+type ValidationResult struct {
+	Scenario   ReferenceScenario
+	Results    *Results
+	KSDistance float64
+	Passed     bool
+}
+
+/**
+ * Reads a `ReferenceDataset` from `path` and checks that it is well formed.
+ *
+ * @param path - The path of the JSON file
+ *
+ * @return - The `ReferenceDataset` and any error reading or validating it
+ */
+func LoadReferenceDataset(path string) (*ReferenceDataset, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset := &ReferenceDataset{}
+	if err := json.Unmarshal(contents, dataset); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := dataset.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return dataset, nil
+}
+
+/**
+ * Verifies that every scenario has a source, a figure and date if the
+ * dataset is from the bulletin, and describes a valid simulation and a
+ * non-decreasing CDF.
+ *
+ * @return - nil if the `ReferenceDataset` is valid, otherwise the first problem
+ */
+func (rd *ReferenceDataset) Validate() error {
+	if rd.MaxKSDistance <= 0.0 || rd.MaxKSDistance > 1.0 {
+		return fmt.Errorf("max KS distance %f is not in (0, 1]", rd.MaxKSDistance)
+	}
+	if len(rd.Scenarios) == 0 {
+		return errors.New("reference dataset has no scenarios")
+	}
+
+	for _, rs := range rd.Scenarios {
+		if err := rs.Config().Validate(); err != nil {
+			return fmt.Errorf("scenario %q: %v", rs.Name, err)
+		}
+		if rs.Source == "" {
+			return fmt.Errorf("scenario %q has no source", rs.Name)
+		}
+		if rd.Bulletin && (rs.Figure == "" || rs.Date == "") {
+			return fmt.Errorf("bulletin scenario %q must cite its figure and date", rs.Name)
+		}
+		if len(rs.Points) == 0 {
+			return fmt.Errorf("scenario %q has no points", rs.Name)
+		}
+		for i, p := range rs.Points {
+			if p.Time <= 0.0 || p.Probability < 0.0 || p.Probability > 1.0 {
+				return fmt.Errorf("scenario %q point %d is invalid", rs.Name, i)
+			}
+			if i > 0 && (p.Time <= rs.Points[i-1].Time || p.Probability < rs.Points[i-1].Probability) {
+				return fmt.Errorf("scenario %q point %d does not increase", rs.Name, i)
+			}
+		}
+	}
+	return nil
+}
+
+/**
+ * Runs every scenario of the dataset.
+ *
+ * @return - The `ValidationResult` of each scenario, in order
+ */
+func (rd *ReferenceDataset) Run() []ValidationResult {
+	validation := make([]ValidationResult, len(rd.Scenarios))
+	for i, rs := range rd.Scenarios {
+		validation[i] = rs.Run(rd.MaxKSDistance)
+	}
+	return validation
+}
+
+/**
+ * Builds the `Config` of the scenario, without any loggers.
+ *
+ * @return - The `Config` of the constant load simulation
+ */
+func (rs ReferenceScenario) Config() *Config {
+	config := DefaultConfig()
+	config.BlockSize = rs.BlockSize
+	config.NumBlocks = rs.NumBlocks
+	config.NumIterations = rs.NumIterations
+	config.Seed = rs.Seed
+	config.UseConstantLoad(rs.Load)
+	config.Loggers = LoggersConfig{SecsPerBucket: DEFAULT_SECS_PER_BUCKET}
+	return config
+}
+
+/**
+ * Simulates the scenario and compares its CDF to the reference.
+ *
+ * @param maxKSDistance - The largest KS distance that passes
+ *
+ * @return - The `ValidationResult` of the scenario
+ */
+func (rs ReferenceScenario) Run(maxKSDistance float64) ValidationResult {
+	lss, err := rs.Config().Simulation()
+	check(err)
+
+	results := lss.Simulate()
+	distance := rs.KSDistance(results)
+
+	return ValidationResult{
+		Scenario:   rs,
+		Results:    results,
+		KSDistance: distance,
+		Passed:     distance <= maxKSDistance,
+	}
+}
+
+/**
+ * Computes the Kolmogorov-Smirnov distance between the simulated CDF and the
+ * reference, evaluated at the reference points since the reference is only
+ * known there.
+ *
+ * @param results - The simulated results of the scenario
+ *
+ * @return - The largest absolute difference between the CDFs
+ */
+func (rs ReferenceScenario) KSDistance(results *Results) float64 {
+	distance := 0.0
+	for _, p := range rs.Points {
+		distance = math.Max(distance, math.Abs(results.Probability(0, p.Time)-p.Probability))
+	}
+	return distance
+}
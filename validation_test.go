@@ -0,0 +1,103 @@
+package bitcoin_load_spike
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const LOW_LOAD_BASELINE_REFERENCE = "reference/low_load_baseline.json"
+
+func TestKSDistance(t *testing.T) {
+	plot := newCumulativePlot()
+	for _, age := range []float64{10, 20, 30, 40} {
+		plot.incrementBucket(confirmationBucket(age))
+	}
+	results := &Results{plots: []*cumulativePlot{plot}}
+
+	if p := results.Probability(0, 25); p != 0.5 {
+		t.Error("Expected half of the txns within 25 seconds, got", p)
+	}
+	if p := results.Probability(0, 1000); p != 1.0 {
+		t.Error("Expected every txn within 1000 seconds, got", p)
+	}
+
+	rs := ReferenceScenario{Points: []ReferencePoint{{15, 0.25}, {25, 0.8}, {100, 1.0}}}
+	if d := rs.KSDistance(results); math.Abs(d-0.3) > 1e-9 {
+		t.Error("Expected KS distance 0.3, got", d)
+	}
+}
+
+func TestInvalidReferenceDataset(t *testing.T) {
+	scenarios := map[string]string{
+		"a decreasing CDF": `{
+			"name": "decreasing", "source": "test", "block_size": 1048576, "load": 0.1, "num_blocks": 10, "num_iterations": 1,
+			"points": [{"time": 60, "probability": 0.5}, {"time": 120, "probability": 0.4}]
+		}`,
+		"a scenario without a source": `{
+			"name": "unsourced", "block_size": 1048576, "load": 0.1, "num_blocks": 10, "num_iterations": 1,
+			"points": [{"time": 60, "probability": 0.5}]
+		}`,
+		"a bulletin scenario without a figure": `{
+			"name": "uncited", "source": "test", "date": "2016-01-01", "block_size": 1048576, "load": 0.1, "num_blocks": 10, "num_iterations": 1,
+			"points": [{"time": 60, "probability": 0.5}]
+		}`,
+	}
+
+	for problem, scenario := range scenarios {
+		path := filepath.Join(t.TempDir(), "reference.json")
+		contents := `{"bulletin": true, "max_ks_distance": 0.05, "scenarios": [` + scenario + `]}`
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadReferenceDataset(path); err == nil {
+			t.Error("Expected", problem, "to be rejected")
+		}
+	}
+}
+
+/**
+ * Runs every scenario of `dataset`, failing the scenarios that
+ * drifted from the reference.
+ */
+func testReference(t *testing.T, dataset *ReferenceDataset) {
+	for _, result := range dataset.Run() {
+		if !result.Passed {
+			t.Errorf("Scenario %q drifted from the reference, KS distance %f exceeds %f",
+				result.Scenario.Name, result.KSDistance, dataset.MaxKSDistance)
+		}
+	}
+}
+
+func TestTrafficBulletinReference(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping reference scenarios in short mode")
+	}
+
+	dataset, err := LoadReferenceDataset(TRAFFIC_BULLETIN_REFERENCE)
+	if os.IsNotExist(err) {
+		t.Skip("The Bitcoin Traffic Bulletin's CDF points have not been transcribed to", TRAFFIC_BULLETIN_REFERENCE)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dataset.Bulletin {
+		t.Fatal("Expected", TRAFFIC_BULLETIN_REFERENCE, "to be a bulletin dataset")
+	}
+	testReference(t, dataset)
+}
+
+func TestLowLoadBaselineReference(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping reference scenarios in short mode")
+	}
+
+	dataset, err := LoadReferenceDataset(LOW_LOAD_BASELINE_REFERENCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testReference(t, dataset)
+}